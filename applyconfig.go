@@ -1,19 +1,17 @@
 package nrc
 
 import (
-	"bytes"
-	"crypto/tls"
+	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"strings"
+	"io"
+	"os"
 )
 
 type applyconfig struct {
-	Output   []string
-	username string
-	password string
+	Output []string
+	client *Client
 }
 
 func (r applyconfig) RequiredOptions() []string {
@@ -29,6 +27,14 @@ func (r applyconfig) OptionsJson() (s string) {
 }
 
 func (r applyconfig) ShowJson(newline, brief bool, filter string) {
+	r.WriteJSON(os.Stdout, newline, brief, filter)
+}
+
+/*
+ * Write the output lines as a json array of strings
+ */
+func (r applyconfig) WriteJSON(w io.Writer, newline, brief bool,
+	filter string) error {
 
 	var nl = ""   // newline
 	var ind4 = "" // big indent
@@ -38,29 +44,55 @@ func (r applyconfig) ShowJson(newline, brief bool, filter string) {
 		ind4 = "    "
 	}
 
+	bw := bufio.NewWriter(w)
+
 	comma := ""
-	fmt.Printf("[")
+	fmt.Fprintf(bw, "[")
 	for _, j := range r.Output {
 		e := new(jsonEncode)
 		e.string(j)
-		fmt.Printf("%s%s%s%s",
+		fmt.Fprintf(bw, "%s%s%s%s",
 			comma, nl, ind4, e.String())
 		comma = ","
 	}
-	fmt.Printf("%s]\n", nl)
+	fmt.Fprintf(bw, "%s]\n", nl)
+
+	return bw.Flush()
 }
 
 func (r applyconfig) Show(brief bool, filter string) {
+	r.WriteText(os.Stdout, brief, filter)
+}
+
+/*
+ * Write the output lines as they were received
+ */
+func (r applyconfig) WriteText(w io.Writer, brief bool, filter string) error {
+
+	bw := bufio.NewWriter(w)
 
 	for _, j := range r.Output {
-		fmt.Printf("%s\n", j)
+		fmt.Fprintf(bw, "%s\n", j)
 	}
+
+	return bw.Flush()
 }
 
+// NewNrcApplyConfig returns a query that skips server certificate
+// verification.
+//
+// Deprecated: Use NewClient, with SetTLS for a CA bundle or pinned
+// fingerprint, and its ApplyConfig method.
 func NewNrcApplyConfig(username, password string) *applyconfig {
+	return newInsecureClient(username, password).ApplyConfig()
+}
+
+/*
+ * Create an applyconfig query that sends its requests through c
+ */
+func (c *Client) ApplyConfig() *applyconfig {
 	r := &applyconfig{}
-	r.username = username
-	r.password = password
+	r.client = c
 	return r
 }
 
@@ -68,84 +100,30 @@ func (r applyconfig) Get(url, endpoint, folder string, data []string) (e error)
 	return nil
 }
 
+func (r applyconfig) GetContext(ctx context.Context, url, endpoint,
+	folder string, data []string) (e error) {
+	return nil
+}
+
 /*
  * Send HTTP POST request
  */
 func (r *applyconfig) Post(url, endpoint, folder string, data []string) (e error) {
+	return r.PostContext(context.Background(), url, endpoint, folder, data)
+}
 
-	for strings.HasSuffix(url, "/") {
-		url = strings.TrimSuffix(url, "/")
-	}
-	for strings.HasSuffix(endpoint, "/") {
-		url = strings.TrimSuffix(endpoint, "/")
-	}
-
-	fullUrl := url + "/" + endpoint
-
-	// Format data
-	dataStr := "json={\"folder\":\"" + folder + "\""
-	dataInr, err := FormatData(data, "")
-	if err != nil {
-		txt := fmt.Sprintf("Could not format data. Check the '-d' option.")
-		return HttpError{txt}
-	}
-	if dataInr != "" {
-		dataStr += "," + dataInr
-	}
-	dataStr += "}"
-
-	//fmt.Printf("URL=%s\n", fullUrl)
-	//fmt.Printf("Data=%s\n", dataStr)
-
-	buf := bytes.NewBuffer([]byte(dataStr))
-
-	//fmt.Printf("json=%s\n", buf)
-
-	// accept bad certs
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-	client := &http.Client{Transport: tr}
+func (r *applyconfig) PostContext(ctx context.Context, url, endpoint,
+	folder string, data []string) (e error) {
 
-	//resp, err := client.Post(fullUrl, "application/x-www-form-urlencoded", buf)
-	req, err := http.NewRequest("POST", fullUrl, buf)
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	if len(r.username) > 0 {
-		req.SetBasicAuth(r.username, r.password)
-	}
-	resp, err := client.Do(req)
+	body, err := clientOrDefault(r.client).post(ctx, url, endpoint, folder,
+		"", data)
 	if err != nil {
-		txt := fmt.Sprintf("Could not send REST request ('%s').", err.Error())
-		return HttpError{txt}
-	}
-
-	defer resp.Body.Close()
-
-	var body []byte
-	if b, err := ioutil.ReadAll(resp.Body); err != nil {
-		txt := fmt.Sprintf("Error reading Body ('%s').", err.Error())
-		return HttpError{txt}
-	} else {
-		body = b
+		return err
 	}
 
-	if resp.StatusCode != 200 {
-
-		response, _ := UrlDecode(string(body))
-		txt := fmt.Sprintf("Status (%d): %s", resp.StatusCode, response)
-		return HttpError{txt}
-
-	} else {
-
-		if err := json.Unmarshal(body, &r.Output); err != nil {
-			txt := fmt.Sprintf("Status (%d) Error decoding JSON (%s).",
-				resp.StatusCode, err.Error())
-			return HttpError{txt}
-		}
-
-		return nil
+	if err := json.Unmarshal(body, &r.Output); err != nil {
+		return DecodeError{body, err}
 	}
 
-	// If we get here then it was a success
 	return nil
 }
@@ -1,24 +1,34 @@
 package nrc
 
 import (
-	"crypto/tls"
+	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"strings"
+	"io"
+	"os"
 )
 
 type check struct {
-	Output   []string
-	username string
-	password string
+	Output []string
+	client *Client
 }
 
+// NewNrcCheck returns a query that skips server certificate
+// verification.
+//
+// Deprecated: Use NewClient, with SetTLS for a CA bundle or pinned
+// fingerprint, and its Check method.
 func NewNrcCheck(username, password string) *check {
+	return newInsecureClient(username, password).Check()
+}
+
+/*
+ * Create a check query that sends its requests through c
+ */
+func (c *Client) Check() *check {
 	r := &check{}
-	r.username = username
-	r.password = password
+	r.client = c
 	return r
 }
 
@@ -35,6 +45,14 @@ func (c check) OptionsJson() (s string) {
 }
 
 func (c check) ShowJson(newline, brief bool, filter string) {
+	c.WriteJSON(os.Stdout, newline, brief, filter)
+}
+
+/*
+ * Write the output lines as a json array of strings
+ */
+func (c check) WriteJSON(w io.Writer, newline, brief bool,
+	filter string) error {
 
 	var nl = ""   // newline
 	var ind4 = "" // big indent
@@ -44,100 +62,68 @@ func (c check) ShowJson(newline, brief bool, filter string) {
 		ind4 = "    "
 	}
 
+	bw := bufio.NewWriter(w)
+
 	comma := ""
-	fmt.Printf("[")
+	fmt.Fprintf(bw, "[")
 	for _, j := range c.Output {
 		e := new(jsonEncode)
 		e.string(j)
-		fmt.Printf("%s%s%s%s",
+		fmt.Fprintf(bw, "%s%s%s%s",
 			comma, nl, ind4, e.String())
 		comma = ","
 	}
-	fmt.Printf("%s]\n", nl)
+	fmt.Fprintf(bw, "%s]\n", nl)
+
+	return bw.Flush()
 }
 
 func (c check) Show(brief bool, filter string) {
+	c.WriteText(os.Stdout, brief, filter)
+}
+
+/*
+ * Write the output lines as they were received
+ */
+func (c check) WriteText(w io.Writer, brief bool, filter string) error {
+
+	bw := bufio.NewWriter(w)
 
 	for _, j := range c.Output {
-		fmt.Printf("%s\n", j)
+		fmt.Fprintf(bw, "%s\n", j)
 	}
+
+	return bw.Flush()
 }
 
 /*
  * Send HTTP GET request
  */
 func (c *check) Get(url, endpoint, folder string, data []string) (e error) {
+	return c.GetContext(context.Background(), url, endpoint, folder, data)
+}
 
-	// accept bad certs
-	tr := &http.Transport{
-		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
-		DisableKeepAlives: true,
-	}
-	client := &http.Client{Transport: tr}
-	// Not available in Go<1.3
-	//client.Timeout = 8 * 1e9
-
-	for strings.HasSuffix(url, "/") {
-		url = strings.TrimSuffix(url, "/")
-	}
-	for strings.HasSuffix(endpoint, "/") {
-		url = strings.TrimSuffix(endpoint, "/")
-	}
-
-	// Construct url, http://1.2.3.4/rest/show/hosts?json={"folder":"local",...}
-	fullUrl := url + "/" + endpoint + "?json={\"folder\":\"" + folder + "\""
-	dataStr, err := FormatData(data, "services")
-	if err != nil {
-		txt := fmt.Sprintf("Could not format data. Check the '-d' option.")
-		return HttpError{txt}
-	}
-	if dataStr != "" {
-		fullUrl += "," + dataStr
-	}
-	fullUrl += "}"
-
-	//fmt.Printf("URL=%s\n", fullUrl)
+func (c *check) GetContext(ctx context.Context, url, endpoint,
+	folder string, data []string) (e error) {
 
-	//fmt.Printf("%s\n", url+"/"+endpoint)
-	//resp, err := client.Get(fullUrl)
-	req, err := http.NewRequest("GET", fullUrl, nil)
-	if len(c.username) > 0 {
-		req.SetBasicAuth(c.username, c.password)
-	}
-	resp, err := client.Do(req)
+	body, err := clientOrDefault(c.client).get(ctx, url, endpoint, folder,
+		"", data)
 	if err != nil {
-		txt := fmt.Sprintf("Could not send REST request ('%s').", err.Error())
-		return HttpError{txt}
+		return err
 	}
 
-	defer resp.Body.Close()
-
-	var body []byte
-	if b, err := ioutil.ReadAll(resp.Body); err != nil {
-		txt := fmt.Sprintf("Error reading Body ('%s').", err.Error())
-		return HttpError{txt}
-	} else {
-		body = b
+	if err := json.Unmarshal(body, &c.Output); err != nil {
+		return DecodeError{body, err}
 	}
 
-	if resp.StatusCode != 200 {
-
-		response, _ := UrlDecode(string(body))
-		txt := fmt.Sprintf("Status (%d): %s", resp.StatusCode, response)
-		return HttpError{txt}
-
-	} else {
-
-		if err := json.Unmarshal(body, &c.Output); err != nil {
-			txt := fmt.Sprintf("Status (%d) Error decoding JSON (%s).",
-				resp.StatusCode, err.Error())
-			return HttpError{txt}
-		}
-
-		return nil
-	}
+	return nil
 }
 
 func (c check) Post(url, endpoint, folder string, data []string) (e error) {
 	return nil
 }
+
+func (c check) PostContext(ctx context.Context, url, endpoint,
+	folder string, data []string) (e error) {
+	return nil
+}
@@ -0,0 +1,201 @@
+package nrc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client holds the connection details shared by every request sent to a
+// nagrestconf REST endpoint. A Client should be created once and reused so
+// that the underlying connections are pooled by its http.Client.
+type Client struct {
+	BaseURL    string // e.g. https://1.2.3.4/
+	Username   string
+	Password   string
+	Folder     string        // used when a request does not name a folder
+	Timeout    time.Duration // per request, zero means no limit
+	Retry      *RetryPolicy  // nil sends each request once
+	HTTPClient *http.Client
+}
+
+// Shared by all clients that do not supply their own http.Client.
+var defaultHTTPClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+	},
+}
+
+// Shared by the deprecated NewNrc* constructors, which have always
+// accepted any server certificate.
+var insecureHTTPClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		// accept bad certs
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	},
+}
+
+// Used by queries that were created without a client.
+var defaultClient = &Client{HTTPClient: insecureHTTPClient}
+
+/*
+ * Create a client that verifies the server certificate. Use SetTLS to
+ * supply a CA bundle, a pinned fingerprint or a client certificate, or to
+ * opt in to insecure mode.
+ */
+func NewClient(baseURL, username, password, folder string) *Client {
+	c := &Client{}
+	c.BaseURL = baseURL
+	c.Username = username
+	c.Password = password
+	c.Folder = folder
+	c.HTTPClient = defaultHTTPClient
+	return c
+}
+
+/*
+ * Create the client used by the deprecated NewNrc* constructors. New
+ * code should use NewClient, which verifies the server certificate.
+ */
+func newInsecureClient(username, password string) *Client {
+	c := NewClient("", username, password, "")
+	c.HTTPClient = insecureHTTPClient
+	return c
+}
+
+func clientOrDefault(c *Client) *Client {
+	if c == nil {
+		return defaultClient
+	}
+	return c
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return defaultHTTPClient
+	}
+	return c.HTTPClient
+}
+
+/*
+ * Join the base url and the endpoint. If url is empty the client's
+ * BaseURL is used instead.
+ */
+func (c *Client) endpointUrl(url, endpoint string) string {
+
+	if url == "" {
+		url = c.BaseURL
+	}
+
+	url = strings.TrimRight(url, "/")
+	endpoint = strings.Trim(endpoint, "/")
+
+	return url + "/" + endpoint
+}
+
+/*
+ * Build the url-encoded json document sent with every request:
+ *   {"folder":"local",...}
+ * Table selects which fields are url-encoded, see EncodeRequest.
+ */
+func (c *Client) requestJson(folder, table string,
+	data []string) (string, error) {
+
+	if folder == "" {
+		folder = c.Folder
+	}
+
+	return EncodeRequest(folder, table, data)
+}
+
+/*
+ * Send HTTP GET request and return the body of a successful reply
+ */
+func (c *Client) get(ctx context.Context, url, endpoint, folder,
+	table string, data []string) ([]byte, error) {
+
+	// Construct url, http://1.2.3.4/rest/show/hosts?json={"folder":"local",...}
+	dataStr, err := c.requestJson(folder, table, data)
+	if err != nil {
+		return nil, err
+	}
+	fullUrl := c.endpointUrl(url, endpoint) + "?json=" + dataStr
+
+	return c.Retry.run(ctx, false, func() ([]byte, error) {
+		return c.do(ctx, "GET", fullUrl, "")
+	})
+}
+
+/*
+ * Send HTTP POST request and return the body of a successful reply
+ */
+func (c *Client) post(ctx context.Context, url, endpoint, folder,
+	table string, data []string) ([]byte, error) {
+
+	fullUrl := c.endpointUrl(url, endpoint)
+
+	// Format data
+	dataStr, err := c.requestJson(folder, table, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Retry.run(ctx, true, func() ([]byte, error) {
+		return c.do(ctx, "POST", fullUrl, "json="+dataStr)
+	})
+}
+
+/*
+ * Send the request, bounded by c.Timeout if one is set, and return the
+ * body of a successful reply
+ */
+func (c *Client) do(ctx context.Context, method, fullUrl,
+	data string) ([]byte, error) {
+
+	if c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	var buf io.Reader
+	if method == "POST" {
+		buf = bytes.NewBufferString(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fullUrl, buf)
+	if err != nil {
+		return nil, TransportError{"send", fullUrl, err}
+	}
+	if method == "POST" {
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	if len(c.Username) > 0 {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, TransportError{"send", fullUrl, err}
+	}
+
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, TransportError{"read", fullUrl, err}
+	}
+
+	if resp.StatusCode != 200 {
+		return nil, newStatusError(resp.StatusCode, body)
+	}
+
+	return body, nil
+}
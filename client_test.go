@@ -0,0 +1,230 @@
+package nrc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	nrc "github.com/mclarkson/nagrestconf-golib"
+	"github.com/mclarkson/nagrestconf-golib/nrctest"
+)
+
+// Values that url-encoding must carry through intact
+const (
+	awkwardCommand = "check_http!-u /a%20b?x=1&y=2+3"
+	awkwardAlias   = `Web & DB "100%" \ primary`
+	awkwardDesc    = "HTTP 50% & up + more"
+)
+
+func TestClientHostRoundTrip(t *testing.T) {
+
+	s := nrctest.NewServer()
+	defer s.Close()
+	c := s.NrcClient("local")
+	ctx := context.Background()
+
+	h := nrc.Host{Name: "web1", Alias: awkwardAlias, Ipaddress: "10.0.0.1",
+		Template: "hsttmpl-local", Command: awkwardCommand}
+	if err := c.Hosts().Add(ctx, h); err != nil {
+		t.Fatal(err)
+	}
+
+	// The server stores what was sent, not its encoding
+	recs := s.Records("local", "hosts")
+	if len(recs) != 1 {
+		t.Fatalf("server has %d hosts, want 1", len(recs))
+	}
+	if recs[0]["command"] != awkwardCommand ||
+		recs[0]["alias"] != awkwardAlias {
+		t.Errorf("server stored command %q alias %q", recs[0]["command"],
+			recs[0]["alias"])
+	}
+
+	got := c.Hosts()
+	if err := got.GetContext(ctx, "", "rest/show/hosts", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if r := got.Records(); len(r) != 1 || r[0] != h {
+		t.Errorf("got %+v, want %+v", r, h)
+	}
+
+	newCommand := awkwardCommand + "&z=%41"
+	if err := c.Hosts().Modify(ctx, nrc.Host{Name: "web1"},
+		map[string]string{"command": newCommand}); err != nil {
+		t.Fatal(err)
+	}
+	got = c.Hosts()
+	if err := got.GetContext(ctx, "", "rest/show/hosts", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if r := got.Records(); len(r) != 1 || r[0].Command != newCommand {
+		t.Errorf("after modify got %+v, want command %q", r, newCommand)
+	}
+
+	if err := c.Hosts().Delete(ctx, nrc.Host{Name: "web1"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(s.Records("local", "hosts")); n != 0 {
+		t.Errorf("server has %d hosts after delete, want 0", n)
+	}
+}
+
+func TestClientEncodedKeys(t *testing.T) {
+
+	s := nrctest.NewServer()
+	defer s.Close()
+	c := s.NrcClient("local")
+	ctx := context.Background()
+
+	// Both key fields of a service are encoded fields
+	svc := nrc.Service{Name: "web 1&2", Template: "svctmpl-local",
+		Command: awkwardCommand, Svcdesc: awkwardDesc}
+	if err := c.Services().Add(ctx, svc); err != nil {
+		t.Fatal(err)
+	}
+
+	key := nrc.Service{Name: svc.Name, Svcdesc: svc.Svcdesc}
+	if err := c.Services().Modify(ctx, key,
+		map[string]string{"command": "check_ping"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Services().Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(s.Records("local", "services")); n != 0 {
+		t.Errorf("server has %d services after delete, want 0", n)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+
+	s := nrctest.NewServer()
+	defer s.Close()
+	c := s.NrcClient("local")
+	ctx := context.Background()
+
+	s.Load("local", "hosts", map[string]string{"name": "web1"})
+
+	err := c.Hosts().Add(ctx, nrc.Host{Name: "web1", Alias: "a",
+		Ipaddress: "10.0.0.1", Template: "t"})
+	if !errors.Is(err, nrc.ErrAlreadyExists) {
+		t.Errorf("add of an existing host: got %v, want ErrAlreadyExists", err)
+	}
+
+	err = c.Hosts().Delete(ctx, nrc.Host{Name: "web2"})
+	if !errors.Is(err, nrc.ErrNotFound) {
+		t.Errorf("delete of a missing host: got %v, want ErrNotFound", err)
+	}
+
+	var verr nrc.ValidationError
+	err = c.Hosts().Add(ctx, nrc.Host{Name: "web3"})
+	if !errors.As(err, &verr) {
+		t.Errorf("add without required fields: got %v, want ValidationError",
+			err)
+	}
+	if n := s.Calls("rest/add/hosts"); n != 1 {
+		t.Errorf("server saw %d adds, want 1", n)
+	}
+}
+
+func TestClientFolders(t *testing.T) {
+
+	s := nrctest.NewServer()
+	defer s.Close()
+	ctx := context.Background()
+
+	s.Load("local", "hosts", map[string]string{"name": "web1"})
+	s.Load("other", "hosts", map[string]string{"name": "db1"},
+		map[string]string{"name": "db2"})
+
+	h := s.NrcClient("other").Hosts()
+	if err := h.GetContext(ctx, "", "rest/show/hosts", "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(h.Records()); n != 2 {
+		t.Errorf("got %d hosts from folder other, want 2", n)
+	}
+
+	h = s.NrcClient("other").Hosts()
+	if err := h.GetContext(ctx, "", "rest/show/hosts", "local",
+		nil); err != nil {
+		t.Fatal(err)
+	}
+	if r := h.Records(); len(r) != 1 || r[0].Name != "web1" {
+		t.Errorf("folder argument ignored, got %+v", r)
+	}
+}
+
+func TestClientGetQuery(t *testing.T) {
+
+	s := nrctest.NewServer()
+	defer s.Close()
+	s.Load("local", "hosts",
+		map[string]string{"name": "web1", "ipaddress": "10.0.0.1"},
+		map[string]string{"name": "db1", "ipaddress": "10.1.0.1"},
+		map[string]string{"name": "web10", "ipaddress": "10.0.0.10"},
+		map[string]string{"name": "mail1", "ipaddress": "10.0.0.5"},
+		map[string]string{"name": "web2", "ipaddress": "10.0.0.2"})
+
+	// Pass requests to the fake server, noting the show data sent
+	var mu sync.Mutex
+	sent := []map[string]string{}
+	proxy := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			req := map[string]string{}
+			r.ParseForm()
+			json.Unmarshal([]byte(r.Form.Get("json")), &req)
+			delete(req, "folder")
+			mu.Lock()
+			sent = append(sent, req)
+			mu.Unlock()
+			s.ServeHTTP(w, r)
+		}))
+	defer proxy.Close()
+	c := nrc.NewClient(proxy.URL, "", "", "local")
+	ctx := context.Background()
+
+	q := nrc.Query{Filter: `ipaddress:^10[.]0[.], name~^web`,
+		Columns: []string{"ipaddress", "name"}, Sort: []string{"-name"}}
+
+	h := c.Hosts()
+	if err := h.GetQuery(ctx, q); err != nil {
+		t.Fatal(err)
+	}
+	q.Local = true
+	local := c.Hosts()
+	if err := local.GetQuery(ctx, q); err != nil {
+		t.Fatal(err)
+	}
+
+	// The ipaddress regex goes to the server, ipaddress being column 3
+	want := []map[string]string{
+		{"column": "3", "filter": "^10[.]0[.]"},
+		{},
+	}
+	if !reflect.DeepEqual(sent, want) {
+		t.Errorf("sent %q, want %q", sent, want)
+	}
+
+	// Filtering again here gives what filtering only here does
+	if !reflect.DeepEqual(h.Records(), local.Records()) {
+		t.Errorf("got %+v, local filtering got %+v", h.Records(),
+			local.Records())
+	}
+
+	var buf bytes.Buffer
+	if err := h.Write(&buf, nrc.CSVFormatter{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	wantCSV := "ipaddress,name\n10.0.0.10,web10\n10.0.0.2,web2\n" +
+		"10.0.0.1,web1\n"
+	if buf.String() != wantCSV {
+		t.Errorf("wrote %q, want %q", buf.String(), wantCSV)
+	}
+}
@@ -1,47 +1,116 @@
+// Code generated by internal/gen from schema.json; DO NOT EDIT.
+
 package nrc
 
 import (
-	"bytes"
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"reflect"
-	"regexp"
+	"io"
+	"os"
 	"sort"
-	"strings"
 )
 
-type command struct {
-	name    string
-	command string
-	disable string
+// Command is one record of the nagrestconf commands table.
+type Command struct {
+	Name    string `json:"name,omitempty"`
+	Command string `json:"command,omitempty"`
+	Disable string `json:"disable,omitempty"`
+}
+
+func (r Command) TableName() string {
+	return "commands"
+}
+
+/*
+ * Return the value of the named field and whether the table has it
+ */
+func (r Command) Field(name string) (string, bool) {
+	switch name {
+	case "name":
+		return r.Name, true
+	case "command":
+		return r.Command, true
+	case "disable":
+		return r.Disable, true
+	}
+	return "", false
+}
+
+/*
+ * Set the named field, returning false if the table has no such field
+ */
+func (r *Command) SetField(name, value string) bool {
+	switch name {
+	case "name":
+		r.Name = value
+	case "command":
+		r.Command = value
+	case "disable":
+		r.Disable = value
+	default:
+		return false
+	}
+	return true
+}
+
+/*
+ * Return the field values in table column order
+ */
+func (r Command) Values() []string {
+	return []string{
+		r.Name,
+		r.Command,
+		r.Disable,
+	}
 }
 
 type Commands struct {
-	commands []command
-	username string
-	password string
+	commands []Command
+	client   *Client
+	columns  []string
+	order    []sortKey
 }
 
 func (h Commands) RequiredOptions() []string {
-	return []string{"name", "command"}
+	t, _ := Table("commands")
+	return t.Required
 }
 
 func (h *Commands) Options() (arr []string) {
 
-	n := reflect.TypeOf(h.commands).Elem().NumField()
-	for i := 0; i < n; i++ {
-		f := reflect.TypeOf(h.commands).Elem().Field(i)
-		arr = append(arr, f.Name)
-	}
+	t, _ := Table("commands")
+	arr = t.Fields
 
 	sort.Strings(arr)
 
 	return arr
 }
 
+/*
+ * Return a copy of the records fetched by Get, in the order set by
+ * SortBy
+ */
+func (h Commands) Records() []Command {
+	return h.sorted()
+}
+
+/*
+ * Return a copy of the records, sorted if SortBy was used
+ */
+func (h Commands) sorted() []Command {
+
+	r := append([]Command(nil), h.commands...)
+
+	if h.order != nil {
+		sort.SliceStable(r, func(i, j int) bool {
+			return compareRecords(h.order, r[i], r[j]) < 0
+		})
+	}
+
+	return r
+}
+
 func (h Commands) OptionsJson() (s string) {
 
 	f := h.Options()
@@ -57,280 +126,290 @@ func (h Commands) OptionsJson() (s string) {
 	return s
 }
 
-func (h *Commands) filterCommands(filter string) {
+/*
+ * Keep only the records that pass the filter expression. Malformed
+ * filters and unknown field names are errors.
+ */
+func (h *Commands) filterCommands(filter string) error {
 
-	f := NewFilter(filter)
+	f, err := CompileFilter(filter)
+	if err != nil {
+		return err
+	}
+	if err := f.Check(h.Options(), "commands"); err != nil {
+		return err
+	}
 
-	newh := []command{}
+	newh := []Command{}
 
 	for _, k := range h.commands {
-		t := &k // Must be a pointer or interface for reflection
-		foundCount := 0
-		for i := 0; i < len(f.names); i++ {
-			_, found := reflect.TypeOf(t).Elem().FieldByName(f.names[i])
-			if found == true {
-				val := reflect.ValueOf(t).Elem().FieldByName(f.names[i])
-				userRegx, _ := UrlDecodeForce(f.regex[i])
-				regex := regexp.MustCompile(userRegx)
-				if regex.MatchString(val.String()) {
-					foundCount += 1
-					continue
-				}
-				/*
-					val := reflect.ValueOf(t).Elem().FieldByName(f.names[i])
-					if val.String() == f.regex[i] {
-						foundCount += 1
-						continue
-					}
-				*/
-			}
-		}
-		if foundCount == len(f.names) {
+		if f.Match(k) {
 			newh = append(newh, k)
 		}
 	}
 
 	// Replace the list we got with this filtered list
 	h.commands = newh
+
+	return nil
 }
 
 func (h Commands) Show(brief bool, filter string) {
-
-	if filter != "" {
-		h.filterCommands(filter)
+	if err := h.WriteText(os.Stdout, brief, filter); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
 	}
+}
 
-	var ind4 = "    " // big indent
-
-	fmt.Printf("\n")
-	for _, r := range h.commands {
-		t := &r // Reflection is only allowed on ptr or interface
-		n := reflect.TypeOf(t).Elem().NumField()
-		for i := 0; i < n; i++ {
-			f := reflect.TypeOf(t).Elem().Field(i)
-			g := reflect.ValueOf(t).Elem().Field(i)
-			if brief == true || (brief == false && g.String() != "") {
-				fmt.Printf("%s%s:%s\n",
-					ind4, f.Name, g)
-			}
-		}
-		fmt.Printf("\n")
-	}
+/*
+ * Write the records as indented field:value blocks
+ */
+func (h Commands) WriteText(w io.Writer, brief bool, filter string) error {
+	return h.Write(w, TextFormatter{ShowEmpty: brief}, filter)
 }
 
 func (h Commands) ShowJson(newline, brief bool, filter string) {
+	if err := h.WriteJSON(os.Stdout, newline, brief, filter); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+	}
+}
+
+/*
+ * Write the records as a json array of objects. Newline set means
+ * everything goes on one line.
+ */
+func (h Commands) WriteJSON(w io.Writer, newline, brief bool,
+	filter string) error {
+	return h.Write(w, JSONFormatter{Compact: newline, ShowEmpty: brief}, filter)
+}
+
+/*
+ * Write the records, after filtering and sorting, using formatter f
+ */
+func (h Commands) Write(w io.Writer, f Formatter, filter string) error {
 
 	if filter != "" {
-		h.filterCommands(filter)
+		if err := h.filterCommands(filter); err != nil {
+			return err
+		}
 	}
 
-	var nl = ""   // newline
-	var ind4 = "" // big indent
-	var ind2 = "" // small indent
+	t, _ := Table("commands")
 
-	if newline == false {
-		nl = "\n"
-		ind4 = "    "
-		ind2 = "  "
+	rows := make([][]string, 0, len(h.commands))
+	for _, r := range h.sorted() {
+		rows = append(rows, r.Values())
 	}
 
-	fmt.Printf("[")
-	objcomma := ""
-	for _, r := range h.commands {
-		comma := ""
-		nli := ""
-		fmt.Printf("%s%s%s{%s", objcomma, nl, ind2, nl)
-		t := &r // Reflection is only allowed on ptr or interface
-		n := reflect.TypeOf(t).Elem().NumField()
-		for i := 0; i < n; i++ {
-			f := reflect.TypeOf(t).Elem().Field(i)
-			g := reflect.ValueOf(t).Elem().Field(i)
-			if brief == true || (brief == false && g.String() != "") {
-				fmt.Printf("%s%s%s\"%s\":\"%s\"",
-					comma, nli, ind4, f.Name, g)
-				comma = ","
-				nli = nl
-			}
+	if h.columns != nil {
+		fields, rows, err := selectColumns(t.Fields, rows, h.columns)
+		if err != nil {
+			return err
 		}
-		fmt.Printf("%s%s}", nl, ind2)
-		objcomma = ","
+		return f.Format(w, fields, rows)
 	}
-	fmt.Printf("%s]\n", nl)
-}
 
-func NewNrcCommands(username, password string) *Commands {
-	h := &Commands{}
-	h.username = username
-	h.password = password
-	return h
+	return f.Format(w, t.Fields, rows)
 }
 
 /*
- * Send HTTP GET request
+ * Choose the fields, and their order, used by every output format.
+ * No columns means all fields again.
  */
-func (h *Commands) Get(url, endpoint, folder string, data []string) (e error) {
+func (h *Commands) Select(columns ...string) error {
 
-	// accept bad certs
-	tr := &http.Transport{
-		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
-		DisableKeepAlives: true,
+	if len(columns) == 0 {
+		h.columns = nil
+		return nil
 	}
-	client := &http.Client{Transport: tr}
-	// Not available in Go<1.3
-	//client.Timeout = 8 * 1e9
 
-	for strings.HasSuffix(url, "/") {
-		url = strings.TrimSuffix(url, "/")
-	}
-	for strings.HasSuffix(endpoint, "/") {
-		url = strings.TrimSuffix(endpoint, "/")
+	if err := checkColumns(h.Options(), "commands", columns); err != nil {
+		return err
 	}
+	h.columns = append([]string{}, columns...)
 
-	// Construct url, http://1.2.3.4/rest/show/hosts?json={"folder":"local",...}
-	fullUrl := url + "/" + endpoint + "?json={\"folder\":\"" + folder + "\""
-	dataStr, err := FormatData(data, "services")
-	if err != nil {
-		txt := fmt.Sprintf("Could not format data. Check the '-d' option.")
-		return HttpError{txt}
-	}
-	if dataStr != "" {
-		fullUrl += "," + dataStr
-	}
-	fullUrl += "}"
+	return nil
+}
 
-	//fmt.Printf("URL=%s\n", fullUrl)
+/*
+ * Set the order of the records returned by Records and written by every
+ * output format. Records are sorted on the first field, then the second
+ * and so on, with runs of digits compared as numbers so web9 comes
+ * before web10. Prefix a field with '-' to sort it descending. No fields
+ * means the order the server returned.
+ */
+func (h *Commands) SortBy(fields ...string) error {
 
-	//fmt.Printf("%s\n", url+"/"+endpoint)
-	//resp, err := client.Get(fullUrl)
-	req, err := http.NewRequest("GET", fullUrl, nil)
-	if len(h.username) > 0 {
-		req.SetBasicAuth(h.username, h.password)
+	if len(fields) == 0 {
+		h.order = nil
+		return nil
 	}
-	resp, err := client.Do(req)
+
+	order, err := parseSort(h.Options(), "commands", fields)
 	if err != nil {
-		txt := fmt.Sprintf("Could not send REST request ('%s').", err.Error())
-		return HttpError{txt}
+		return err
 	}
+	h.order = order
 
-	defer resp.Body.Close()
+	return nil
+}
 
-	var body []byte
-	if b, err := ioutil.ReadAll(resp.Body); err != nil {
-		txt := fmt.Sprintf("Error reading Body ('%s').", err.Error())
-		return HttpError{txt}
-	} else {
-		body = b
-	}
+// NewNrcCommands returns a query that skips server certificate
+// verification.
+//
+// Deprecated: Use NewClient, with SetTLS for a CA bundle or pinned
+// fingerprint, and its Commands method.
+func NewNrcCommands(username, password string) *Commands {
+	return newInsecureClient(username, password).Commands()
+}
 
-	if resp.StatusCode != 200 {
+/*
+ * Create a Commands query that sends its requests through c
+ */
+func (c *Client) Commands() *Commands {
+	h := &Commands{}
+	h.client = c
+	return h
+}
 
-		response, _ := UrlDecode(string(body))
-		txt := fmt.Sprintf("Status (%d): %s", resp.StatusCode, response)
-		return HttpError{txt}
+/*
+ * Send HTTP GET request
+ */
+func (h *Commands) Get(url, endpoint, folder string, data []string) (e error) {
+	return h.GetContext(context.Background(), url, endpoint, folder, data)
+}
 
-	} else {
+func (h *Commands) GetContext(ctx context.Context, url, endpoint,
+	folder string, data []string) (e error) {
 
-		var generic interface{}
-		if err := json.Unmarshal(body, &generic); err != nil {
-			txt := fmt.Sprintf("Status (%d) Error decoding JSON (%s).",
-				resp.StatusCode, err.Error())
-			return HttpError{txt}
-		}
-		genericReply := generic.([]interface{})
-
-		//fmt.Printf("%s\n", body)
-
-		for _, j := range genericReply {
-			command := command{}
-			for _, j2 := range j.([]interface{}) {
-				content := j2.(map[string]interface{})
-				for k, v := range content {
-					switch k {
-					case "name":
-						command.name, _ = UrlDecode(v.(string))
-					case "command":
-						command.command, _ = UrlDecode(v.(string))
-					case "disable":
-						command.disable = v.(string)
-					}
+	body, err := clientOrDefault(h.client).get(ctx, url, endpoint, folder,
+		"commands", data)
+	if err != nil {
+		return err
+	}
+
+	// [[{"name":"web1"},{"alias":"Web server"},...],...]
+	var reply [][]map[string]string
+	if err := json.Unmarshal(body, &reply); err != nil {
+		return DecodeError{body, err}
+	}
+
+	t, _ := Table("commands")
+
+	for _, j := range reply {
+		command := Command{}
+		for _, content := range j {
+			for k, val := range content {
+				if t.IsEncoded(k) {
+					val, _ = UrlDecode(val)
 				}
+				command.SetField(k, val)
 			}
-			h.commands = append(h.commands, command)
 		}
-
-		return nil
+		h.commands = append(h.commands, command)
 	}
+
+	return nil
 }
 
 /*
- * Send HTTP POST request
+ * Fetch the records of the client's folder that pass q's filter, then
+ * apply q's column selection and sort order. See Query for which part of
+ * the filter the server is asked to apply.
  */
-func (h Commands) Post(url, endpoint, folder string, data []string) (e error) {
+func (h *Commands) GetQuery(ctx context.Context, q Query) error {
+
+	t, _ := Table("commands")
 
-	for strings.HasSuffix(url, "/") {
-		url = strings.TrimSuffix(url, "/")
+	data, f, err := showData(t, q)
+	if err != nil {
+		return err
 	}
-	for strings.HasSuffix(endpoint, "/") {
-		url = strings.TrimSuffix(endpoint, "/")
+	if len(q.Columns) > 0 {
+		if err := checkColumns(h.Options(), "commands", q.Columns); err != nil {
+			return err
+		}
+	}
+	order := []sortKey(nil)
+	if len(q.Sort) > 0 {
+		if order, err = parseSort(h.Options(), "commands", q.Sort); err != nil {
+			return err
+		}
 	}
 
-	fullUrl := url + "/" + endpoint
+	got := Commands{client: h.client}
+	if err := got.GetContext(ctx, "", "rest/show/commands", "", data); err != nil {
+		return err
+	}
 
-	// Format data
-	dataStr := "json={\"folder\":\"" + folder + "\""
-	dataInr, err := FormatData(data, "commands")
-	if err != nil {
-		txt := fmt.Sprintf("Could not format data. Check the '-d' option.")
-		return HttpError{txt}
+	for _, k := range got.commands {
+		if f == nil || f.Match(k) {
+			h.commands = append(h.commands, k)
+		}
 	}
-	if dataInr != "" {
-		dataStr += "," + dataInr
+	if len(q.Columns) > 0 {
+		h.columns = append([]string{}, q.Columns...)
 	}
-	dataStr += "}"
+	if order != nil {
+		h.order = order
+	}
+
+	return nil
+}
+
+/*
+ * Send HTTP POST request
+ */
+func (h Commands) Post(url, endpoint, folder string, data []string) (e error) {
+	return h.PostContext(context.Background(), url, endpoint, folder, data)
+}
 
-	//fmt.Printf("URL=%s\n", fullUrl)
-	//fmt.Printf("Data=%s\n", dataStr)
+func (h Commands) PostContext(ctx context.Context, url, endpoint,
+	folder string, data []string) (e error) {
 
-	buf := bytes.NewBuffer([]byte(dataStr))
+	_, err := clientOrDefault(h.client).post(ctx, url, endpoint, folder,
+		"commands", data)
 
-	//fmt.Printf("json=%s\n", buf)
+	return err
+}
 
-	// accept bad certs
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-	client := &http.Client{Transport: tr}
+/*
+ * Add a record to the client's folder. The required fields are checked
+ * before anything is sent.
+ */
+func (h Commands) Add(ctx context.Context, r Command) error {
 
-	//resp, err := client.Post(fullUrl, "application/x-www-form-urlencoded", buf)
-	req, err := http.NewRequest("POST", fullUrl, buf)
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	if len(h.username) > 0 {
-		req.SetBasicAuth(h.username, h.password)
-	}
-	resp, err := client.Do(req)
+	data, err := addData("commands", r)
 	if err != nil {
-		txt := fmt.Sprintf("Could not send REST request ('%s').", err.Error())
-		return HttpError{txt}
+		return err
 	}
 
-	defer resp.Body.Close()
+	return h.PostContext(ctx, "", "rest/add/commands", "", data)
+}
 
-	var body []byte
-	if b, err := ioutil.ReadAll(resp.Body); err != nil {
-		txt := fmt.Sprintf("Error reading Body ('%s').", err.Error())
-		return HttpError{txt}
-	} else {
-		body = b
+/*
+ * Modify the record identified by the key fields of key. Changes map
+ * field names to their new values.
+ */
+func (h Commands) Modify(ctx context.Context, key Command,
+	changes map[string]string) error {
+
+	data, err := modifyData("commands", key, changes)
+	if err != nil {
+		return err
 	}
 
-	if resp.StatusCode != 200 {
+	return h.PostContext(ctx, "", "rest/modify/commands", "", data)
+}
 
-		response, _ := UrlDecode(string(body))
-		txt := fmt.Sprintf("Status (%d): %s", resp.StatusCode, response)
-		return HttpError{txt}
+/*
+ * Delete the record identified by the key fields of key
+ */
+func (h Commands) Delete(ctx context.Context, key Command) error {
 
+	data, err := keyData("commands", key)
+	if err != nil {
+		return err
 	}
 
-	// If we get here then it was a success
-	return nil
+	return h.PostContext(ctx, "", "rest/delete/commands", "", data)
 }
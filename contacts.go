@@ -1,68 +1,242 @@
+// Code generated by internal/gen from schema.json; DO NOT EDIT.
+
 package nrc
 
 import (
-	"bytes"
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"reflect"
-	"regexp"
+	"io"
+	"os"
 	"sort"
-	"strings"
 )
 
-type contact struct {
-	name                string
-	use                 string
-	alias               string
-	emailaddr           string
-	svcnotifperiod      string
-	svcnotifopts        string
-	svcnotifcmds        string
-	hstnotifperiod      string
-	hstnotifopts        string
-	hstnotifcmds        string
-	cansubmitcmds       string
-	disable             string
-	svcnotifenabled     string
-	hstnotifenabled     string
-	pager               string
-	address1            string
-	address2            string
-	address3            string
-	address4            string
-	address5            string
-	address6            string
-	retainstatusinfo    string
-	retainnonstatusinfo string
-	contactgroups       string
+// Contact is one record of the nagrestconf contacts table.
+type Contact struct {
+	Name                string `json:"name,omitempty"`
+	Use                 string `json:"use,omitempty"`
+	Alias               string `json:"alias,omitempty"`
+	Emailaddr           string `json:"emailaddr,omitempty"`
+	Svcnotifperiod      string `json:"svcnotifperiod,omitempty"`
+	Svcnotifopts        string `json:"svcnotifopts,omitempty"`
+	Svcnotifcmds        string `json:"svcnotifcmds,omitempty"`
+	Hstnotifperiod      string `json:"hstnotifperiod,omitempty"`
+	Hstnotifopts        string `json:"hstnotifopts,omitempty"`
+	Hstnotifcmds        string `json:"hstnotifcmds,omitempty"`
+	Cansubmitcmds       string `json:"cansubmitcmds,omitempty"`
+	Disable             string `json:"disable,omitempty"`
+	Svcnotifenabled     string `json:"svcnotifenabled,omitempty"`
+	Hstnotifenabled     string `json:"hstnotifenabled,omitempty"`
+	Pager               string `json:"pager,omitempty"`
+	Address1            string `json:"address1,omitempty"`
+	Address2            string `json:"address2,omitempty"`
+	Address3            string `json:"address3,omitempty"`
+	Address4            string `json:"address4,omitempty"`
+	Address5            string `json:"address5,omitempty"`
+	Address6            string `json:"address6,omitempty"`
+	Retainstatusinfo    string `json:"retainstatusinfo,omitempty"`
+	Retainnonstatusinfo string `json:"retainnonstatusinfo,omitempty"`
+	Contactgroups       string `json:"contactgroups,omitempty"`
+}
+
+func (r Contact) TableName() string {
+	return "contacts"
+}
+
+/*
+ * Return the value of the named field and whether the table has it
+ */
+func (r Contact) Field(name string) (string, bool) {
+	switch name {
+	case "name":
+		return r.Name, true
+	case "use":
+		return r.Use, true
+	case "alias":
+		return r.Alias, true
+	case "emailaddr":
+		return r.Emailaddr, true
+	case "svcnotifperiod":
+		return r.Svcnotifperiod, true
+	case "svcnotifopts":
+		return r.Svcnotifopts, true
+	case "svcnotifcmds":
+		return r.Svcnotifcmds, true
+	case "hstnotifperiod":
+		return r.Hstnotifperiod, true
+	case "hstnotifopts":
+		return r.Hstnotifopts, true
+	case "hstnotifcmds":
+		return r.Hstnotifcmds, true
+	case "cansubmitcmds":
+		return r.Cansubmitcmds, true
+	case "disable":
+		return r.Disable, true
+	case "svcnotifenabled":
+		return r.Svcnotifenabled, true
+	case "hstnotifenabled":
+		return r.Hstnotifenabled, true
+	case "pager":
+		return r.Pager, true
+	case "address1":
+		return r.Address1, true
+	case "address2":
+		return r.Address2, true
+	case "address3":
+		return r.Address3, true
+	case "address4":
+		return r.Address4, true
+	case "address5":
+		return r.Address5, true
+	case "address6":
+		return r.Address6, true
+	case "retainstatusinfo":
+		return r.Retainstatusinfo, true
+	case "retainnonstatusinfo":
+		return r.Retainnonstatusinfo, true
+	case "contactgroups":
+		return r.Contactgroups, true
+	}
+	return "", false
+}
+
+/*
+ * Set the named field, returning false if the table has no such field
+ */
+func (r *Contact) SetField(name, value string) bool {
+	switch name {
+	case "name":
+		r.Name = value
+	case "use":
+		r.Use = value
+	case "alias":
+		r.Alias = value
+	case "emailaddr":
+		r.Emailaddr = value
+	case "svcnotifperiod":
+		r.Svcnotifperiod = value
+	case "svcnotifopts":
+		r.Svcnotifopts = value
+	case "svcnotifcmds":
+		r.Svcnotifcmds = value
+	case "hstnotifperiod":
+		r.Hstnotifperiod = value
+	case "hstnotifopts":
+		r.Hstnotifopts = value
+	case "hstnotifcmds":
+		r.Hstnotifcmds = value
+	case "cansubmitcmds":
+		r.Cansubmitcmds = value
+	case "disable":
+		r.Disable = value
+	case "svcnotifenabled":
+		r.Svcnotifenabled = value
+	case "hstnotifenabled":
+		r.Hstnotifenabled = value
+	case "pager":
+		r.Pager = value
+	case "address1":
+		r.Address1 = value
+	case "address2":
+		r.Address2 = value
+	case "address3":
+		r.Address3 = value
+	case "address4":
+		r.Address4 = value
+	case "address5":
+		r.Address5 = value
+	case "address6":
+		r.Address6 = value
+	case "retainstatusinfo":
+		r.Retainstatusinfo = value
+	case "retainnonstatusinfo":
+		r.Retainnonstatusinfo = value
+	case "contactgroups":
+		r.Contactgroups = value
+	default:
+		return false
+	}
+	return true
+}
+
+/*
+ * Return the field values in table column order
+ */
+func (r Contact) Values() []string {
+	return []string{
+		r.Name,
+		r.Use,
+		r.Alias,
+		r.Emailaddr,
+		r.Svcnotifperiod,
+		r.Svcnotifopts,
+		r.Svcnotifcmds,
+		r.Hstnotifperiod,
+		r.Hstnotifopts,
+		r.Hstnotifcmds,
+		r.Cansubmitcmds,
+		r.Disable,
+		r.Svcnotifenabled,
+		r.Hstnotifenabled,
+		r.Pager,
+		r.Address1,
+		r.Address2,
+		r.Address3,
+		r.Address4,
+		r.Address5,
+		r.Address6,
+		r.Retainstatusinfo,
+		r.Retainnonstatusinfo,
+		r.Contactgroups,
+	}
 }
 
 type Contacts struct {
-	contacts []contact
-	username string
-	password string
+	contacts []Contact
+	client   *Client
+	columns  []string
+	order    []sortKey
 }
 
 func (h Contacts) RequiredOptions() []string {
-	return []string{"name", "alias", "svcnotifperiod", "svcnotifopts", "svcnotifcmds", "hstnotifperiod", "hstnotifopts", "hstnotifcmds"}
+	t, _ := Table("contacts")
+	return t.Required
 }
 
 func (h *Contacts) Options() (arr []string) {
 
-	n := reflect.TypeOf(h.contacts).Elem().NumField()
-	for i := 0; i < n; i++ {
-		f := reflect.TypeOf(h.contacts).Elem().Field(i)
-		arr = append(arr, f.Name)
-	}
+	t, _ := Table("contacts")
+	arr = t.Fields
 
 	sort.Strings(arr)
 
 	return arr
 }
 
+/*
+ * Return a copy of the records fetched by Get, in the order set by
+ * SortBy
+ */
+func (h Contacts) Records() []Contact {
+	return h.sorted()
+}
+
+/*
+ * Return a copy of the records, sorted if SortBy was used
+ */
+func (h Contacts) sorted() []Contact {
+
+	r := append([]Contact(nil), h.contacts...)
+
+	if h.order != nil {
+		sort.SliceStable(r, func(i, j int) bool {
+			return compareRecords(h.order, r[i], r[j]) < 0
+		})
+	}
+
+	return r
+}
+
 func (h Contacts) OptionsJson() (s string) {
 
 	f := h.Options()
@@ -78,322 +252,290 @@ func (h Contacts) OptionsJson() (s string) {
 	return s
 }
 
-func (h *Contacts) filterContacts(filter string) {
+/*
+ * Keep only the records that pass the filter expression. Malformed
+ * filters and unknown field names are errors.
+ */
+func (h *Contacts) filterContacts(filter string) error {
 
-	f := NewFilter(filter)
+	f, err := CompileFilter(filter)
+	if err != nil {
+		return err
+	}
+	if err := f.Check(h.Options(), "contacts"); err != nil {
+		return err
+	}
 
-	newh := []contact{}
+	newh := []Contact{}
 
 	for _, k := range h.contacts {
-		t := &k // Must be a pointer or interface for reflection
-		foundCount := 0
-		for i := 0; i < len(f.names); i++ {
-			_, found := reflect.TypeOf(t).Elem().FieldByName(f.names[i])
-			if found == true {
-				val := reflect.ValueOf(t).Elem().FieldByName(f.names[i])
-				userRegx, _ := UrlDecodeForce(f.regex[i])
-				regex := regexp.MustCompile(userRegx)
-				if regex.MatchString(val.String()) {
-					foundCount += 1
-					continue
-				}
-				/*
-					val := reflect.ValueOf(t).Elem().FieldByName(f.names[i])
-					if val.String() == f.regex[i] {
-						foundCount += 1
-						continue
-					}
-				*/
-			}
-		}
-		if foundCount == len(f.names) {
+		if f.Match(k) {
 			newh = append(newh, k)
 		}
 	}
 
 	// Replace the list we got with this filtered list
 	h.contacts = newh
+
+	return nil
 }
 
 func (h Contacts) Show(brief bool, filter string) {
-
-	if filter != "" {
-		h.filterContacts(filter)
+	if err := h.WriteText(os.Stdout, brief, filter); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
 	}
+}
 
-	var ind4 = "    " // big indent
-
-	fmt.Printf("\n")
-	for _, r := range h.contacts {
-		t := &r // Reflection is only allowed on ptr or interface
-		n := reflect.TypeOf(t).Elem().NumField()
-		for i := 0; i < n; i++ {
-			f := reflect.TypeOf(t).Elem().Field(i)
-			g := reflect.ValueOf(t).Elem().Field(i)
-			if brief == true || (brief == false && g.String() != "") {
-				fmt.Printf("%s%s:%s\n",
-					ind4, f.Name, g)
-			}
-		}
-		fmt.Printf("\n")
-	}
+/*
+ * Write the records as indented field:value blocks
+ */
+func (h Contacts) WriteText(w io.Writer, brief bool, filter string) error {
+	return h.Write(w, TextFormatter{ShowEmpty: brief}, filter)
 }
 
 func (h Contacts) ShowJson(newline, brief bool, filter string) {
+	if err := h.WriteJSON(os.Stdout, newline, brief, filter); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+	}
+}
+
+/*
+ * Write the records as a json array of objects. Newline set means
+ * everything goes on one line.
+ */
+func (h Contacts) WriteJSON(w io.Writer, newline, brief bool,
+	filter string) error {
+	return h.Write(w, JSONFormatter{Compact: newline, ShowEmpty: brief}, filter)
+}
+
+/*
+ * Write the records, after filtering and sorting, using formatter f
+ */
+func (h Contacts) Write(w io.Writer, f Formatter, filter string) error {
 
 	if filter != "" {
-		h.filterContacts(filter)
+		if err := h.filterContacts(filter); err != nil {
+			return err
+		}
 	}
 
-	var nl = ""   // newline
-	var ind4 = "" // big indent
-	var ind2 = "" // small indent
+	t, _ := Table("contacts")
 
-	if newline == false {
-		nl = "\n"
-		ind4 = "    "
-		ind2 = "  "
+	rows := make([][]string, 0, len(h.contacts))
+	for _, r := range h.sorted() {
+		rows = append(rows, r.Values())
 	}
 
-	fmt.Printf("[")
-	objcomma := ""
-	for _, r := range h.contacts {
-		comma := ""
-		nli := ""
-		fmt.Printf("%s%s%s{%s", objcomma, nl, ind2, nl)
-		t := &r // Reflection is only allowed on ptr or interface
-		n := reflect.TypeOf(t).Elem().NumField()
-		for i := 0; i < n; i++ {
-			f := reflect.TypeOf(t).Elem().Field(i)
-			g := reflect.ValueOf(t).Elem().Field(i)
-			if brief == true || (brief == false && g.String() != "") {
-				fmt.Printf("%s%s%s\"%s\":\"%s\"",
-					comma, nli, ind4, f.Name, g)
-				comma = ","
-				nli = nl
-			}
+	if h.columns != nil {
+		fields, rows, err := selectColumns(t.Fields, rows, h.columns)
+		if err != nil {
+			return err
 		}
-		fmt.Printf("%s%s}", nl, ind2)
-		objcomma = ","
+		return f.Format(w, fields, rows)
 	}
-	fmt.Printf("%s]\n", nl)
-}
 
-func NewNrcContacts(username, password string) *Contacts {
-	h := &Contacts{}
-	h.username = username
-	h.password = password
-	return h
+	return f.Format(w, t.Fields, rows)
 }
 
 /*
- * Send HTTP GET request
+ * Choose the fields, and their order, used by every output format.
+ * No columns means all fields again.
  */
-func (h *Contacts) Get(url, endpoint, folder string, data []string) (e error) {
+func (h *Contacts) Select(columns ...string) error {
 
-	// accept bad certs
-	tr := &http.Transport{
-		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
-		DisableKeepAlives: true,
+	if len(columns) == 0 {
+		h.columns = nil
+		return nil
 	}
-	client := &http.Client{Transport: tr}
-	// Not available in Go<1.3
-	//client.Timeout = 8 * 1e9
 
-	for strings.HasSuffix(url, "/") {
-		url = strings.TrimSuffix(url, "/")
-	}
-	for strings.HasSuffix(endpoint, "/") {
-		url = strings.TrimSuffix(endpoint, "/")
+	if err := checkColumns(h.Options(), "contacts", columns); err != nil {
+		return err
 	}
+	h.columns = append([]string{}, columns...)
 
-	// Construct url, http://1.2.3.4/rest/show/hosts?json={"folder":"local",...}
-	fullUrl := url + "/" + endpoint + "?json={\"folder\":\"" + folder + "\""
-	dataStr, err := FormatData(data, "services")
-	if err != nil {
-		txt := fmt.Sprintf("Could not format data. Check the '-d' option.")
-		return HttpError{txt}
-	}
-	if dataStr != "" {
-		fullUrl += "," + dataStr
-	}
-	fullUrl += "}"
+	return nil
+}
 
-	//fmt.Printf("URL=%s\n", fullUrl)
+/*
+ * Set the order of the records returned by Records and written by every
+ * output format. Records are sorted on the first field, then the second
+ * and so on, with runs of digits compared as numbers so web9 comes
+ * before web10. Prefix a field with '-' to sort it descending. No fields
+ * means the order the server returned.
+ */
+func (h *Contacts) SortBy(fields ...string) error {
 
-	//fmt.Printf("%s\n", url+"/"+endpoint)
-	//resp, err := client.Get(fullUrl)
-	req, err := http.NewRequest("GET", fullUrl, nil)
-	if len(h.username) > 0 {
-		req.SetBasicAuth(h.username, h.password)
+	if len(fields) == 0 {
+		h.order = nil
+		return nil
 	}
-	resp, err := client.Do(req)
+
+	order, err := parseSort(h.Options(), "contacts", fields)
 	if err != nil {
-		txt := fmt.Sprintf("Could not send REST request ('%s').", err.Error())
-		return HttpError{txt}
+		return err
 	}
+	h.order = order
 
-	defer resp.Body.Close()
+	return nil
+}
 
-	var body []byte
-	if b, err := ioutil.ReadAll(resp.Body); err != nil {
-		txt := fmt.Sprintf("Error reading Body ('%s').", err.Error())
-		return HttpError{txt}
-	} else {
-		body = b
-	}
+// NewNrcContacts returns a query that skips server certificate
+// verification.
+//
+// Deprecated: Use NewClient, with SetTLS for a CA bundle or pinned
+// fingerprint, and its Contacts method.
+func NewNrcContacts(username, password string) *Contacts {
+	return newInsecureClient(username, password).Contacts()
+}
 
-	if resp.StatusCode != 200 {
+/*
+ * Create a Contacts query that sends its requests through c
+ */
+func (c *Client) Contacts() *Contacts {
+	h := &Contacts{}
+	h.client = c
+	return h
+}
 
-		response, _ := UrlDecode(string(body))
-		txt := fmt.Sprintf("Status (%d): %s", resp.StatusCode, response)
-		return HttpError{txt}
+/*
+ * Send HTTP GET request
+ */
+func (h *Contacts) Get(url, endpoint, folder string, data []string) (e error) {
+	return h.GetContext(context.Background(), url, endpoint, folder, data)
+}
 
-	} else {
+func (h *Contacts) GetContext(ctx context.Context, url, endpoint,
+	folder string, data []string) (e error) {
 
-		var generic interface{}
-		if err := json.Unmarshal(body, &generic); err != nil {
-			txt := fmt.Sprintf("Status (%d) Error decoding JSON (%s).",
-				resp.StatusCode, err.Error())
-			return HttpError{txt}
-		}
-		genericReply := generic.([]interface{})
-
-		//fmt.Printf("%s\n", body)
-
-		for _, j := range genericReply {
-			contact := contact{}
-			for _, j2 := range j.([]interface{}) {
-				content := j2.(map[string]interface{})
-				for k, v := range content {
-					switch k {
-					case "name":
-						contact.name = v.(string)
-					case "use":
-						contact.use = v.(string)
-					case "alias":
-						contact.alias = v.(string)
-					case "emailaddr":
-						contact.emailaddr = v.(string)
-					case "svcnotifperiod":
-						contact.svcnotifperiod = v.(string)
-					case "svcnotifopts":
-						contact.svcnotifopts = v.(string)
-					case "svcnotifcmds":
-						contact.svcnotifcmds = v.(string)
-					case "hstnotifperiod":
-						contact.hstnotifperiod = v.(string)
-					case "hstnotifopts":
-						contact.hstnotifopts = v.(string)
-					case "hstnotifcmds":
-						contact.hstnotifcmds = v.(string)
-					case "cansubmitcmds":
-						contact.cansubmitcmds = v.(string)
-					case "disable":
-						contact.disable = v.(string)
-					case "svcnotifenabled":
-						contact.svcnotifenabled = v.(string)
-					case "hstnotifenabled":
-						contact.hstnotifenabled = v.(string)
-					case "pager":
-						contact.pager = v.(string)
-					case "address1":
-						contact.address1 = v.(string)
-					case "address2":
-						contact.address2 = v.(string)
-					case "address3":
-						contact.address3 = v.(string)
-					case "address4":
-						contact.address4 = v.(string)
-					case "address5":
-						contact.address5 = v.(string)
-					case "address6":
-						contact.address6 = v.(string)
-					case "retainstatusinfo":
-						contact.retainstatusinfo = v.(string)
-					case "retainnonstatusinfo":
-						contact.retainnonstatusinfo = v.(string)
-					case "contactgroups":
-						contact.contactgroups = v.(string)
-					}
+	body, err := clientOrDefault(h.client).get(ctx, url, endpoint, folder,
+		"contacts", data)
+	if err != nil {
+		return err
+	}
+
+	// [[{"name":"web1"},{"alias":"Web server"},...],...]
+	var reply [][]map[string]string
+	if err := json.Unmarshal(body, &reply); err != nil {
+		return DecodeError{body, err}
+	}
+
+	t, _ := Table("contacts")
+
+	for _, j := range reply {
+		contact := Contact{}
+		for _, content := range j {
+			for k, val := range content {
+				if t.IsEncoded(k) {
+					val, _ = UrlDecode(val)
 				}
+				contact.SetField(k, val)
 			}
-			h.contacts = append(h.contacts, contact)
 		}
-
-		return nil
+		h.contacts = append(h.contacts, contact)
 	}
+
+	return nil
 }
 
 /*
- * Send HTTP POST request
+ * Fetch the records of the client's folder that pass q's filter, then
+ * apply q's column selection and sort order. See Query for which part of
+ * the filter the server is asked to apply.
  */
-func (h Contacts) Post(url, endpoint, folder string, data []string) (e error) {
+func (h *Contacts) GetQuery(ctx context.Context, q Query) error {
+
+	t, _ := Table("contacts")
 
-	for strings.HasSuffix(url, "/") {
-		url = strings.TrimSuffix(url, "/")
+	data, f, err := showData(t, q)
+	if err != nil {
+		return err
 	}
-	for strings.HasSuffix(endpoint, "/") {
-		url = strings.TrimSuffix(endpoint, "/")
+	if len(q.Columns) > 0 {
+		if err := checkColumns(h.Options(), "contacts", q.Columns); err != nil {
+			return err
+		}
+	}
+	order := []sortKey(nil)
+	if len(q.Sort) > 0 {
+		if order, err = parseSort(h.Options(), "contacts", q.Sort); err != nil {
+			return err
+		}
 	}
 
-	fullUrl := url + "/" + endpoint
+	got := Contacts{client: h.client}
+	if err := got.GetContext(ctx, "", "rest/show/contacts", "", data); err != nil {
+		return err
+	}
 
-	// Format data
-	dataStr := "json={\"folder\":\"" + folder + "\""
-	dataInr, err := FormatData(data, "contacts")
-	if err != nil {
-		txt := fmt.Sprintf("Could not format data. Check the '-d' option.")
-		return HttpError{txt}
+	for _, k := range got.contacts {
+		if f == nil || f.Match(k) {
+			h.contacts = append(h.contacts, k)
+		}
 	}
-	if dataInr != "" {
-		dataStr += "," + dataInr
+	if len(q.Columns) > 0 {
+		h.columns = append([]string{}, q.Columns...)
 	}
-	dataStr += "}"
+	if order != nil {
+		h.order = order
+	}
+
+	return nil
+}
+
+/*
+ * Send HTTP POST request
+ */
+func (h Contacts) Post(url, endpoint, folder string, data []string) (e error) {
+	return h.PostContext(context.Background(), url, endpoint, folder, data)
+}
 
-	//fmt.Printf("URL=%s\n", fullUrl)
-	//fmt.Printf("Data=%s\n", dataStr)
+func (h Contacts) PostContext(ctx context.Context, url, endpoint,
+	folder string, data []string) (e error) {
 
-	buf := bytes.NewBuffer([]byte(dataStr))
+	_, err := clientOrDefault(h.client).post(ctx, url, endpoint, folder,
+		"contacts", data)
 
-	//fmt.Printf("json=%s\n", buf)
+	return err
+}
 
-	// accept bad certs
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-	client := &http.Client{Transport: tr}
+/*
+ * Add a record to the client's folder. The required fields are checked
+ * before anything is sent.
+ */
+func (h Contacts) Add(ctx context.Context, r Contact) error {
 
-	//resp, err := client.Post(fullUrl, "application/x-www-form-urlencoded", buf)
-	req, err := http.NewRequest("POST", fullUrl, buf)
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	if len(h.username) > 0 {
-		req.SetBasicAuth(h.username, h.password)
-	}
-	resp, err := client.Do(req)
+	data, err := addData("contacts", r)
 	if err != nil {
-		txt := fmt.Sprintf("Could not send REST request ('%s').", err.Error())
-		return HttpError{txt}
+		return err
 	}
 
-	defer resp.Body.Close()
+	return h.PostContext(ctx, "", "rest/add/contacts", "", data)
+}
 
-	var body []byte
-	if b, err := ioutil.ReadAll(resp.Body); err != nil {
-		txt := fmt.Sprintf("Error reading Body ('%s').", err.Error())
-		return HttpError{txt}
-	} else {
-		body = b
+/*
+ * Modify the record identified by the key fields of key. Changes map
+ * field names to their new values.
+ */
+func (h Contacts) Modify(ctx context.Context, key Contact,
+	changes map[string]string) error {
+
+	data, err := modifyData("contacts", key, changes)
+	if err != nil {
+		return err
 	}
 
-	if resp.StatusCode != 200 {
+	return h.PostContext(ctx, "", "rest/modify/contacts", "", data)
+}
 
-		response, _ := UrlDecode(string(body))
-		txt := fmt.Sprintf("Status (%d): %s", resp.StatusCode, response)
-		return HttpError{txt}
+/*
+ * Delete the record identified by the key fields of key
+ */
+func (h Contacts) Delete(ctx context.Context, key Contact) error {
 
+	data, err := keyData("contacts", key)
+	if err != nil {
+		return err
 	}
 
-	// If we get here then it was a success
-	return nil
+	return h.PostContext(ctx, "", "rest/delete/contacts", "", data)
 }
@@ -0,0 +1,181 @@
+package nrc
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+/*
+ * nagrestconf's CSV table format has one line per record holding every
+ * column of the table in schema order, with no header line. Fields the
+ * table stores url-encoded, such as a host's command, are url-encoded in
+ * the file too. A folder is kept as one file per table, hosts.csv,
+ * services.csv and so on.
+ */
+
+/*
+ * Write records, all from one table, in CSV table format
+ */
+func WriteCSV(w io.Writer, records []Record) error {
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	table := records[0].TableName()
+	t, _ := Table(table)
+
+	rows := make([][]string, 0, len(records))
+	for _, r := range records {
+		if r.TableName() != table {
+			return ValidationError{table, nil,
+				fmt.Sprintf("Cannot write %s and %s records together.",
+					table, r.TableName())}
+		}
+		row := r.Values()
+		for i, f := range t.Fields {
+			if t.IsEncoded(f) {
+				row[i] = UrlEncodeForce(row[i])
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return CSVFormatter{NoHeader: true}.Format(w, t.Fields, rows)
+}
+
+/*
+ * Read records of table in CSV table format. Records are pointers to the
+ * table's record type. Lines with fewer columns than the table, as
+ * written by older nagrestconf versions, have the rest left empty.
+ */
+func ReadCSV(r io.Reader, table string) ([]Record, error) {
+
+	t, ok := Table(table)
+	if !ok {
+		return nil, ValidationError{table, nil,
+			fmt.Sprintf("Unknown table '%s'.", table)}
+	}
+
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	out := []Record{}
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(row) > len(t.Fields) {
+			line, _ := cr.FieldPos(0)
+			return nil, fmt.Errorf("line %d: %d columns but %s has %d",
+				line, len(row), table, len(t.Fields))
+		}
+		rec := newRecord(table)
+		for i, v := range row {
+			if t.IsEncoded(t.Fields[i]) {
+				if v, err = UrlDecodeForce(v); err != nil {
+					line, _ := cr.FieldPos(i)
+					return nil, fmt.Errorf("line %d: bad url-encoding in %s",
+						line, t.Fields[i])
+				}
+			}
+			rec.SetField(t.Fields[i], v)
+		}
+		out = append(out, rec)
+	}
+
+	return out, nil
+}
+
+/*
+ * Fetch every table of the client's folder and write each to dir in CSV
+ * table format. Empty tables give empty files so that deletions show up
+ * when snapshots are compared.
+ */
+func SnapshotCSV(ctx context.Context, c *Client, dir string) error {
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for _, table := range TableNames() {
+		recs, err := fetchRecords(ctx, c, table)
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(dir, table+".csv")
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		err = WriteCSV(f, recs)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return nil
+}
+
+/*
+ * Add every record in the CSV files in dir to the client's folder, a
+ * table at a time in dependency order. Missing files are skipped. The
+ * first failure stops the load.
+ */
+func LoadCSV(ctx context.Context, c *Client, dir string) error {
+
+	for _, table := range dependencyOrder {
+
+		path := filepath.Join(dir, table+".csv")
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		recs, err := ReadCSV(f, table)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+
+		for n, r := range recs {
+			if err := addRecord(ctx, c, r); err != nil {
+				return fmt.Errorf("%s: record %d: %w", path, n+1, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+ * Send an add request for r
+ */
+func addRecord(ctx context.Context, c *Client, r Record) error {
+
+	table := r.TableName()
+
+	data, err := addData(table, r)
+	if err != nil {
+		return err
+	}
+
+	_, err = clientOrDefault(c).post(ctx, "", "rest/add/"+table, "", table,
+		data)
+
+	return err
+}
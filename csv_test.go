@@ -0,0 +1,88 @@
+package nrc_test
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	nrc "github.com/mclarkson/nagrestconf-golib"
+	"github.com/mclarkson/nagrestconf-golib/nrctest"
+)
+
+func TestCSVEncodedFields(t *testing.T) {
+
+	svc := &nrc.Service{Name: "web 1", Template: "svctmpl-local",
+		Command: awkwardCommand, Svcdesc: awkwardDesc, Notes: "a, \"b\""}
+
+	var buf bytes.Buffer
+	if err := nrc.WriteCSV(&buf, []nrc.Record{svc}); err != nil {
+		t.Fatal(err)
+	}
+
+	// name, command and svcdesc are written url-encoded, notes is not
+	want := strings.Join([]string{url.QueryEscape(svc.Name), svc.Template,
+		url.QueryEscape(svc.Command), url.QueryEscape(svc.Svcdesc)}, ",")
+	if !strings.HasPrefix(buf.String(), want+",") {
+		t.Errorf("wrote %q, want it to start %q", buf.String(), want)
+	}
+	if !strings.Contains(buf.String(), `"a, ""b"""`) {
+		t.Errorf("notes not written as plain CSV in %q", buf.String())
+	}
+
+	recs, err := nrc.ReadCSV(&buf, "services")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 1 || !reflect.DeepEqual(recs[0], nrc.Record(svc)) {
+		t.Errorf("read back %+v, want %+v", recs, svc)
+	}
+}
+
+func TestReadCSVBadEncoding(t *testing.T) {
+
+	_, err := nrc.ReadCSV(strings.NewReader("web1,tmpl,check%zz,PING\n"),
+		"services")
+	if err == nil || !strings.Contains(err.Error(), "command") {
+		t.Errorf("got %v, want an error about command", err)
+	}
+}
+
+func TestCSVSnapshotLoad(t *testing.T) {
+
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	from := nrctest.NewServer()
+	defer from.Close()
+	from.Load("local", "hosts", map[string]string{"name": "web1",
+		"alias": awkwardAlias, "ipaddress": "10.0.0.1",
+		"template": "hsttmpl-local", "command": awkwardCommand})
+	from.Load("local", "services", map[string]string{"name": "web1",
+		"template": "svctmpl-local", "command": awkwardCommand,
+		"svcdesc": awkwardDesc})
+
+	if err := nrc.SnapshotCSV(ctx, from.NrcClient("local"), dir); err != nil {
+		t.Fatal(err)
+	}
+
+	to := nrctest.NewServer()
+	defer to.Close()
+	if err := nrc.LoadCSV(ctx, to.NrcClient("local"), dir); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, table := range []string{"hosts", "services"} {
+		got, want := to.Records("local", table), from.Records("local", table)
+		if len(got) != 1 {
+			t.Fatalf("%s: loaded %d records, want 1", table, len(got))
+		}
+		for k, v := range want[0] {
+			if got[0][k] != v {
+				t.Errorf("%s %s: loaded %q, want %q", table, k, got[0][k], v)
+			}
+		}
+	}
+}
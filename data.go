@@ -1,49 +1,152 @@
 package nrc
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
 	"strings"
 )
 
-func FormatData(data []string, table string) (string, error) {
-
-	// data string: host:asdf,ipaddress:1.2.3.4
-	// to: "host":"asdf","ipaddress":"1.2.3.4"
-
-	m := make(map[string][]string)
+/*
+ * Split "field:value" items into a map. Later items replace earlier ones.
+ */
+func parseData(data []string) (map[string]string, error) {
 
-	m["hosts"] = []string{"command", "alias"}
-	m["services"] = []string{"name", "command", "svcdesc"}
-	m["servicesets"] = []string{"name", "command", "svcdesc"}
-	m["hosttemplates"] = []string{"checkcommand", "action_url"}
-	m["servicetemplates"] = []string{"action_url"}
-	m["commands"] = []string{"name", "command"}
-
-	returnString := ""
-	comma := ""
+	m := make(map[string]string)
 
 	for _, j := range data {
 		split := strings.SplitN(j, ":", 2)
 		if len(split) < 2 {
-			return "", HttpError{}
+			txt := fmt.Sprintf("Invalid data '%s', expected field:value.", j)
+			return nil, ValidationError{Fields: []string{j}, Message: txt}
 		}
-		var encode = false
-		// check whether field should be encoded
-		for _, i := range m[table] {
-			if i == split[0] {
-				encode = true
-			}
+		m[split[0]] = split[1]
+	}
+
+	return m, nil
+}
+
+/*
+ * Marshal v without the html escaping encoding/json does by default
+ */
+func marshalJson(v interface{}) (string, error) {
+
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+/*
+ * Url-encode the fields of m that table stores url-encoded
+ */
+func encodeFields(table string, m map[string]string) {
+
+	t, _ := Table(table)
+	for _, i := range t.Encoded {
+		if val, ok := m[i]; ok {
+			m[i] = UrlEncodeForce(val)
 		}
-		if encode == false {
-			returnString += comma + `"` + split[0] + `":"`
-			returnString += split[1] + `"`
-		} else {
-			returnString += comma + `"` + split[0] + `":"`
-			val := strings.Replace(split[1], `"`, `\"`, -1)
-			val = UrlEncodeForce(val)
-			returnString += val + `"`
+	}
+}
+
+/*
+ * Reverse encodeFields
+ */
+func decodeFields(table string, m map[string]string) error {
+
+	t, _ := Table(table)
+	for _, i := range t.Encoded {
+		if val, ok := m[i]; ok {
+			v, err := UrlDecodeForce(val)
+			if err != nil {
+				return err
+			}
+			m[i] = v
 		}
-		comma = ","
 	}
 
-	return returnString, nil
+	return nil
+}
+
+/*
+ * Format data for inclusion in a raw json document.
+ *
+ *   data string: host:asdf,ipaddress:1.2.3.4
+ *   to: "host":"asdf","ipaddress":"1.2.3.4"
+ *
+ * Fields the table stores url-encoded are url-encoded here too. Requests
+ * sent by a Client use EncodeRequest instead.
+ */
+func FormatData(data []string, table string) (string, error) {
+
+	m, err := parseData(data)
+	if err != nil {
+		return "", err
+	}
+
+	encodeFields(table, m)
+
+	s, err := marshalJson(m)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(strings.TrimPrefix(s, "{"), "}"), nil
+}
+
+/*
+ * Build the json document sent with a request,
+ *
+ *   {"folder":"local","name":"web1",...}
+ *
+ * and url-encode it as the value of the 'json' form field, so every
+ * field reaches the server intact whatever characters it contains.
+ * Fields that table stores url-encoded, such as the command of a host,
+ * are url-encoded inside the document as well. Table may be empty for
+ * requests that are not about a table.
+ */
+func EncodeRequest(folder, table string, data []string) (string, error) {
+
+	m, err := parseData(data)
+	if err != nil {
+		return "", err
+	}
+	encodeFields(table, m)
+	m["folder"] = folder
+
+	s, err := marshalJson(m)
+	if err != nil {
+		return "", err
+	}
+
+	return url.QueryEscape(s), nil
+}
+
+/*
+ * Reverse EncodeRequest, as the server does, returning every field of the
+ * request including the folder.
+ */
+func DecodeRequest(value, table string) (map[string]string, error) {
+
+	s, err := url.QueryUnescape(value)
+	if err != nil {
+		return nil, DecodeError{[]byte(value), err}
+	}
+
+	m := make(map[string]string)
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		return nil, DecodeError{[]byte(s), err}
+	}
+	if err := decodeFields(table, m); err != nil {
+		return nil, DecodeError{[]byte(s), err}
+	}
+
+	return m, nil
 }
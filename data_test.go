@@ -0,0 +1,145 @@
+package nrc_test
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/url"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	nrc "github.com/mclarkson/nagrestconf-golib"
+)
+
+// A random request: a folder, a table and some of its fields
+type request struct {
+	Folder string
+	Table  string
+	Fields map[string]string
+}
+
+// Characters that have broken requests before
+var awkward = []string{`"`, `\`, "%", "+", "&", "=", ":", ",", " ", "\n",
+	"\t", "<", ">", "{", "}", "#", "?", "/", "é", "日本"}
+
+func randomValue(r *rand.Rand) string {
+
+	s := ""
+	for n := r.Intn(8); n > 0; n-- {
+		if r.Intn(2) == 0 {
+			s += awkward[r.Intn(len(awkward))]
+		} else {
+			v, _ := quick.Value(reflect.TypeOf(""), r)
+			s += v.String()
+		}
+	}
+
+	return s
+}
+
+func (request) Generate(r *rand.Rand, size int) reflect.Value {
+
+	names := nrc.TableNames()
+	t, _ := nrc.Table(names[r.Intn(len(names))])
+
+	q := request{Folder: randomValue(r), Table: t.Name}
+	q.Fields = make(map[string]string)
+	for _, f := range t.Fields {
+		if r.Intn(3) == 0 {
+			q.Fields[f] = randomValue(r)
+		}
+	}
+	// Always include an encoded field when the table has one
+	if len(t.Encoded) > 0 {
+		q.Fields[t.Encoded[r.Intn(len(t.Encoded))]] = randomValue(r)
+	}
+
+	return reflect.ValueOf(q)
+}
+
+func (q request) data() []string {
+	data := []string{}
+	for k, v := range q.Fields {
+		data = append(data, k+":"+v)
+	}
+	return data
+}
+
+func TestEncodeRequestRoundTrip(t *testing.T) {
+
+	f := func(q request) bool {
+
+		value, err := nrc.EncodeRequest(q.Folder, q.Table, q.data())
+		if err != nil {
+			t.Logf("encode: %v", err)
+			return false
+		}
+
+		got, err := nrc.DecodeRequest(value, q.Table)
+		if err != nil {
+			t.Logf("decode: %v", err)
+			return false
+		}
+
+		want := map[string]string{"folder": q.Folder}
+		for k, v := range q.Fields {
+			want[k] = v
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Logf("got %q, want %q", got, want)
+			return false
+		}
+		return true
+	}
+
+	if err := quick.Check(f, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEncodeRequestEncodesTableFields(t *testing.T) {
+
+	f := func(q request) bool {
+
+		value, err := nrc.EncodeRequest(q.Folder, q.Table, q.data())
+		if err != nil {
+			return false
+		}
+
+		// What the server sees before it decodes the table's fields
+		s, err := url.QueryUnescape(value)
+		if err != nil {
+			return false
+		}
+		raw := map[string]string{}
+		if err := json.Unmarshal([]byte(s), &raw); err != nil {
+			return false
+		}
+
+		info, _ := nrc.Table(q.Table)
+		for k, v := range q.Fields {
+			want := v
+			if info.IsEncoded(k) {
+				want = url.QueryEscape(v)
+			}
+			if raw[k] != want {
+				t.Logf("%s %s: sent %q, want %q", q.Table, k, raw[k], want)
+				return false
+			}
+		}
+		return raw["folder"] == q.Folder
+	}
+
+	if err := quick.Check(f, &quick.Config{MaxCount: 2000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEncodeRequestBadData(t *testing.T) {
+
+	if _, err := nrc.EncodeRequest("local", "hosts",
+		[]string{"name"}); err == nil {
+		t.Error("expected an error for data with no colon")
+	}
+}
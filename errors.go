@@ -0,0 +1,134 @@
+package nrc
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Matched with errors.Is against errors returned by the server.
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrAlreadyExists = errors.New("already exists")
+)
+
+// TransportError is returned when a request could not be sent or its
+// reply could not be read.
+type TransportError struct {
+	Op  string // "send" or "read"
+	URL string
+	Err error
+}
+
+func (e TransportError) Error() string {
+	if e.Op == "read" {
+		return fmt.Sprintf("Error reading Body ('%s').", e.Err.Error())
+	}
+	return fmt.Sprintf("Could not send REST request ('%s').", e.Err.Error())
+}
+
+func (e TransportError) Unwrap() error {
+	return e.Err
+}
+
+// FingerprintError is returned, wrapped in a TransportError, when the
+// server's certificate does not match TLSOptions.Fingerprint.
+type FingerprintError struct {
+	Fingerprint string // SHA-256 of the certificate presented, hex
+}
+
+func (e FingerprintError) Error() string {
+	if e.Fingerprint == "" {
+		return "Server presented no certificate."
+	}
+	return fmt.Sprintf("Server certificate fingerprint %s does not match.",
+		e.Fingerprint)
+}
+
+// StatusError is returned when the server replies with a status other
+// than 200. Message holds the decoded reply body.
+type StatusError struct {
+	StatusCode int
+	Message    string
+	Body       []byte
+}
+
+/*
+ * Create a StatusError from a reply. nagrestconf sends its messages as a
+ * json array of lines or as plain, possibly url-encoded, text.
+ */
+func newStatusError(code int, body []byte) StatusError {
+
+	e := StatusError{StatusCode: code, Body: body}
+
+	var lines []string
+	var line string
+	if err := json.Unmarshal(body, &lines); err == nil {
+		e.Message = strings.Join(lines, "\n")
+	} else if err := json.Unmarshal(body, &line); err == nil {
+		e.Message = line
+	} else {
+		e.Message, _ = UrlDecode(string(body))
+	}
+
+	return e
+}
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("Status (%d): %s", e.StatusCode, e.Message)
+}
+
+/*
+ * Match ErrNotFound and ErrAlreadyExists from the status code or the
+ * wording of the server's message. Only nagrestconf's "Item not found"
+ * means the record is missing; other messages mentioning something not
+ * existing, such as a template named by the record, do not.
+ */
+func (e StatusError) Is(target error) bool {
+
+	msg := strings.ToLower(e.Message)
+
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == 404 || strings.Contains(msg, "item not found")
+	case ErrAlreadyExists:
+		return e.StatusCode == 409 || strings.Contains(msg, "already exist")
+	}
+
+	return false
+}
+
+/*
+ * Server side failures (5xx) are usually worth retrying
+ */
+func (e StatusError) Temporary() bool {
+	return e.StatusCode >= 500
+}
+
+// DecodeError is returned when a successful reply is not in the format
+// expected for the request.
+type DecodeError struct {
+	Body []byte
+	Err  error
+}
+
+func (e DecodeError) Error() string {
+	return fmt.Sprintf("Status (200) Error decoding JSON (%s).", e.Err.Error())
+}
+
+func (e DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned, before anything is sent, when a request
+// is incomplete or malformed. Fields lists the offending field names.
+type ValidationError struct {
+	Table   string
+	Fields  []string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return e.Message
+}
@@ -0,0 +1,42 @@
+package nrc_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	nrc "github.com/mclarkson/nagrestconf-golib"
+)
+
+func TestStatusErrorIs(t *testing.T) {
+
+	tests := []struct {
+		code     int
+		msg      string
+		notFound bool
+		exists   bool
+	}{
+		{404, "Not Found", true, false},
+		{400, "ERROR: Item not found.", true, false},
+		{400, "ERROR: item NOT FOUND", true, false},
+		{400, "ERROR: Template 'x' does not exist.", false, false},
+		{400, "ERROR: Host 'web1' does not exist.", false, false},
+		{400, "ERROR: Command not found in path.", false, false},
+		{400, "ERROR: Item already exists.", false, true},
+		{409, "Conflict", false, true},
+		{500, "Internal Server Error", false, false},
+	}
+
+	for _, i := range tests {
+		err := fmt.Errorf("delete hosts web1: %w",
+			nrc.StatusError{StatusCode: i.code, Message: i.msg})
+		if got := errors.Is(err, nrc.ErrNotFound); got != i.notFound {
+			t.Errorf("%d %q: Is(ErrNotFound) = %v, want %v", i.code, i.msg,
+				got, i.notFound)
+		}
+		if got := errors.Is(err, nrc.ErrAlreadyExists); got != i.exists {
+			t.Errorf("%d %q: Is(ErrAlreadyExists) = %v, want %v", i.code,
+				i.msg, got, i.exists)
+		}
+	}
+}
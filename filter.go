@@ -1,31 +1,466 @@
 package nrc
 
 import (
+	"fmt"
+	"regexp"
 	"strings"
 )
 
-type filterStruct struct {
-	names []string
-	regex []string
+/*
+ * Filter expressions select records by their field values.
+ *
+ *   name:web.*,alias:prod        both regexes match (the original syntax)
+ *   name:web|db                  one regex, as it always was
+ *   name~web | name~db           either matches
+ *   !(hostgroup=linux)           not equal, grouping
+ *   name~^web, notes!~temp       regex, negated regex
+ *   name="web1", alias!="x"      equality, inequality
+ *   empty(notes), nonempty(parents)
+ *   alias:"a, b"                 quoted values may hold any character,
+ *                                with \" and \\ escaped
+ *
+ * ',' and '&' mean AND, '|' means OR and binds less tightly, '!' means
+ * NOT. Unquoted values run to the next top level ',', '&', '|' or ')'
+ * and have surrounding spaces trimmed; parentheses inside them must
+ * balance. Values after ':' are the exception: '|' and '&' belong to
+ * the regex, so only ',' or ')' ends them. Use '~' to combine a regex
+ * with '|' or '&'.
+ *
+ * Unquoted values after ':' are url-decoded, as they always were,
+ * unless SetEncode(true) is in effect. Values of the other operators,
+ * and quoted values, are taken as written, so '+' stays a '+'.
+ */
+
+// Filter is a compiled filter expression
+type Filter struct {
+	expr string
+	root filterNode
+}
+
+// FilterError describes a filter expression that could not be compiled.
+// Pos is the byte offset in Expr where the problem was found.
+type FilterError struct {
+	Expr string
+	Pos  int
+	Msg  string
+}
+
+func (e FilterError) Error() string {
+	return fmt.Sprintf("Invalid filter at position %d: %s.", e.Pos+1, e.Msg)
+}
+
+type filterNode interface {
+	match(r Record) bool
+}
+
+type filterAnd []filterNode
+type filterOr []filterNode
+
+type filterNot struct {
+	n filterNode
+}
+
+type filterTest struct {
+	field string
+	op    string // ":", "~", "!~", "=", "!=", "empty", "nonempty"
+	value string
+	regex *regexp.Regexp
+}
+
+func (n filterAnd) match(r Record) bool {
+	for _, i := range n {
+		if !i.match(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n filterOr) match(r Record) bool {
+	for _, i := range n {
+		if i.match(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n filterNot) match(r Record) bool {
+	return !n.n.match(r)
+}
+
+func (n filterTest) match(r Record) bool {
+
+	val, found := r.Field(n.field)
+	if !found {
+		return false
+	}
+
+	switch n.op {
+	case ":", "~":
+		return n.regex.MatchString(val)
+	case "!~":
+		return !n.regex.MatchString(val)
+	case "=":
+		return val == n.value
+	case "!=":
+		return val != n.value
+	case "empty":
+		return val == ""
+	case "nonempty":
+		return val != ""
+	}
+
+	return false
+}
+
+/*
+ * Compile a filter expression. An empty expression matches everything.
+ */
+func CompileFilter(expr string) (*Filter, error) {
+
+	f := &Filter{expr: expr}
+
+	p := &filterParser{s: expr}
+	p.skipSpace()
+	if p.eof() {
+		f.root = filterAnd{}
+		return f, nil
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if !p.eof() {
+		return nil, p.errorf("unexpected '%c'", p.s[p.pos])
+	}
+	f.root = root
+
+	return f, nil
+}
+
+/*
+ * Report whether record r passes the filter
+ */
+func (f *Filter) Match(r Record) bool {
+	return f.root.match(r)
+}
+
+func (f *Filter) String() string {
+	return f.expr
+}
+
+/*
+ * Return the field names the filter tests, each once, in the order they
+ * first appear
+ */
+func (f *Filter) Fields() []string {
+
+	fields := []string{}
+	seen := make(map[string]bool)
+
+	var walk func(n filterNode)
+	walk = func(n filterNode) {
+		switch n := n.(type) {
+		case filterAnd:
+			for _, i := range n {
+				walk(i)
+			}
+		case filterOr:
+			for _, i := range n {
+				walk(i)
+			}
+		case filterNot:
+			walk(n.n)
+		case filterTest:
+			if !seen[n.field] {
+				seen[n.field] = true
+				fields = append(fields, n.field)
+			}
+		}
+	}
+	walk(f.root)
+
+	return fields
+}
+
+/*
+ * Check that every field the filter tests is one of options, the fields
+ * of table. A misspelt field would otherwise just match nothing.
+ */
+func (f *Filter) Check(options []string, table string) error {
+
+	valid := make(map[string]bool)
+	for _, i := range options {
+		valid[i] = true
+	}
+
+	unknown := []string{}
+	for _, i := range f.Fields() {
+		if !valid[i] {
+			unknown = append(unknown, i)
+		}
+	}
+	if len(unknown) > 0 {
+		txt := fmt.Sprintf("Unknown field(s) in filter for %s: %s.", table,
+			strings.Join(unknown, ", "))
+		return ValidationError{table, unknown, txt}
+	}
+
+	return nil
+}
+
+type filterParser struct {
+	s   string
+	pos int
+}
+
+func (p *filterParser) eof() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *filterParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *filterParser) skipSpace() {
+	for !p.eof() && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *filterParser) errorf(format string, a ...interface{}) error {
+	return FilterError{p.s, p.pos, fmt.Sprintf(format, a...)}
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+
+	n, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	or := filterOr{n}
+
+	for {
+		p.skipSpace()
+		if p.peek() != '|' {
+			break
+		}
+		p.pos++
+		n, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, n)
+	}
+
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
 }
 
-func NewFilter(filter string) filterStruct {
+func (p *filterParser) parseAnd() (filterNode, error) {
 
-	f := filterStruct{}
+	n, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	and := filterAnd{n}
 
-	if len(filter) > 0 {
-		filters := strings.Split(filter, ",")
-		for _, j := range filters {
-			split := strings.SplitN(j, ":", 2)
-			if encode == false {
-				f.names = append(f.names, split[0])
-				f.regex = append(f.regex, split[1])
-			} else {
-				f.names = append(f.names, UrlEncodeForce(split[0]))
-				f.regex = append(f.regex, UrlEncodeForce(split[1]))
+	for {
+		p.skipSpace()
+		if c := p.peek(); c != ',' && c != '&' {
+			break
+		}
+		p.pos++
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, n)
+	}
+
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+
+	p.skipSpace()
+
+	switch p.peek() {
+	case '!':
+		p.pos++
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return filterNot{n}, nil
+	case '(':
+		p.pos++
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.peek() != ')' {
+			return nil, p.errorf("missing ')'")
+		}
+		p.pos++
+		return n, nil
+	}
+
+	return p.parseTest()
+}
+
+func isFieldChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9')
+}
+
+func (p *filterParser) parseTest() (filterNode, error) {
+
+	start := p.pos
+	for !p.eof() && isFieldChar(p.s[p.pos]) {
+		p.pos++
+	}
+	field := p.s[start:p.pos]
+	if field == "" {
+		if p.eof() {
+			return nil, p.errorf("expected a field name")
+		}
+		return nil, p.errorf("expected a field name, found '%c'", p.peek())
+	}
+
+	// empty(field), nonempty(field)
+	if (field == "empty" || field == "nonempty") && p.peek() == '(' {
+		p.pos++
+		p.skipSpace()
+		argStart := p.pos
+		for !p.eof() && isFieldChar(p.s[p.pos]) {
+			p.pos++
+		}
+		arg := p.s[argStart:p.pos]
+		if arg == "" {
+			return nil, p.errorf("expected a field name in %s()", field)
+		}
+		p.skipSpace()
+		if p.peek() != ')' {
+			return nil, p.errorf("missing ')' after %s(%s", field, arg)
+		}
+		p.pos++
+		return filterTest{field: arg, op: field}, nil
+	}
+
+	p.skipSpace()
+	opStart := p.pos
+	op := ""
+	for _, i := range []string{"!~", "!=", ":", "~", "="} {
+		if strings.HasPrefix(p.s[p.pos:], i) {
+			op = i
+			break
+		}
+	}
+	if op == "" {
+		if p.eof() {
+			return nil, p.errorf("expected an operator after '%s'", field)
+		}
+		return nil, p.errorf("expected an operator after '%s', found '%c'",
+			field, p.peek())
+	}
+	p.pos += len(op)
+
+	value, err := p.parseValue(op == ":")
+	if err != nil {
+		return nil, err
+	}
+
+	t := filterTest{field: field, op: op, value: value}
+	if op == ":" || op == "~" || op == "!~" {
+		if t.regex, err = regexp.Compile(value); err != nil {
+			return nil, FilterError{p.s, opStart,
+				fmt.Sprintf("bad regular expression for '%s' (%s)",
+					field, err.Error())}
+		}
+	}
+
+	return t, nil
+}
+
+/*
+ * Parse a quoted or bare value. Bare values of the original ':' syntax
+ * are regexes that may hold '|' and '&', so only ',' or an unbalanced
+ * ')' ends them, and are url-decoded as the original filter did.
+ */
+func (p *filterParser) parseValue(original bool) (string, error) {
+
+	p.skipSpace()
+
+	if p.peek() == '"' {
+		start := p.pos
+		p.pos++
+		var b strings.Builder
+		for {
+			if p.eof() {
+				p.pos = start
+				return "", p.errorf("unterminated quoted value")
 			}
+			c := p.s[p.pos]
+			p.pos++
+			if c == '"' {
+				return b.String(), nil
+			}
+			if c == '\\' && !p.eof() {
+				c = p.s[p.pos]
+				p.pos++
+			}
+			b.WriteByte(c)
+		}
+	}
+
+	start := p.pos
+	depth := 0
+loop:
+	for ; !p.eof(); p.pos++ {
+		switch p.s[p.pos] {
+		case '\\':
+			p.pos++ // keep escaped characters, e.g. \), as they are
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				break loop
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				break loop
+			}
+		case '&', '|':
+			if depth == 0 && !original {
+				break loop
+			}
+		}
+	}
+	if p.pos > len(p.s) {
+		p.pos = len(p.s)
+	}
+	value := strings.TrimSpace(p.s[start:p.pos])
+
+	if original && !encode {
+		// So %2C can stand for a comma
+		if v, err := UrlDecodeForce(value); err == nil {
+			value = v
 		}
 	}
 
-	return f
+	return value, nil
 }
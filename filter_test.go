@@ -0,0 +1,162 @@
+package nrc_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	nrc "github.com/mclarkson/nagrestconf-golib"
+)
+
+var filterHosts = []nrc.Host{
+	{Name: "web1", Alias: "prod web", Hostgroup: "linux"},
+	{Name: "web2", Alias: "test web", Hostgroup: "linux", Notes: "temp"},
+	{Name: "db1", Alias: "prod db", Hostgroup: "linux"},
+	{Name: "mail1", Alias: "a&b", Hostgroup: "windows"},
+	{Name: "web,3", Alias: "100%25 up"},
+	{Name: "web12"},
+	{Name: "a+b"},
+}
+
+/*
+ * Return the names of the hosts that pass filter expr
+ */
+func filterNames(t *testing.T, expr string) []string {
+
+	f, err := nrc.CompileFilter(expr)
+	if err != nil {
+		t.Fatalf("%s: %v", expr, err)
+	}
+
+	names := []string{}
+	for _, h := range filterHosts {
+		if f.Match(h) {
+			names = append(names, h.Name)
+		}
+	}
+
+	return names
+}
+
+func TestFilterMatch(t *testing.T) {
+
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"", []string{"web1", "web2", "db1", "mail1", "web,3", "web12",
+			"a+b"}},
+
+		// The original syntax, where ':' takes a regex to the next ','
+		{"name:web", []string{"web1", "web2", "web,3", "web12"}},
+		{"name:web|db", []string{"web1", "web2", "db1", "web,3", "web12"}},
+		{"name:^(web|db)1$", []string{"web1", "db1"}},
+		{"name:web.*,alias:prod", []string{"web1"}},
+		{"name:web|db,alias:prod", []string{"web1", "db1"}},
+		{"alias:a&b", []string{"mail1"}},
+		{"(name:web|db),hostgroup:linux", []string{"web1", "web2", "db1"}},
+
+		{"name~web | name~db", []string{"web1", "web2", "db1", "web,3",
+			"web12"}},
+		{"name~web & alias~prod", []string{"web1"}},
+		{"!(hostgroup=linux)", []string{"mail1", "web,3", "web12", "a+b"}},
+		{"hostgroup=linux, notes!~temp", []string{"web1", "db1"}},
+		{`alias="prod web"`, []string{"web1"}},
+		{`alias!="prod web", hostgroup="linux"`, []string{"web2", "db1"}},
+		{"empty(notes), hostgroup=linux", []string{"web1", "db1"}},
+		{"nonempty(notes)", []string{"web2"}},
+		{`name:"web,3"`, []string{"web,3"}},
+
+		// Only the original ':' syntax url-decodes, so '+' is written %2B
+		{`name~^web\d+$`, []string{"web1", "web2", "web12"}},
+		{`name~^a\+b$`, []string{"a+b"}},
+		{"name=a+b", []string{"a+b"}},
+		{"name!=a+b, name~^(a|m)", []string{"mail1"}},
+		{"name:^web[0-9]%2B$", []string{"web1", "web2", "web12"}},
+	}
+
+	for _, i := range tests {
+		if got := filterNames(t, i.expr); !reflect.DeepEqual(got, i.want) {
+			t.Errorf("%s: got %q, want %q", i.expr, got, i.want)
+		}
+	}
+}
+
+func TestFilterDecode(t *testing.T) {
+
+	// Bare ':' values are url-decoded unless encode is set. Other
+	// operators and quoted values never are.
+	tests := []struct {
+		encode bool
+		expr   string
+		want   []string
+	}{
+		{false, "name:^web%2C3$", []string{"web,3"}},
+		{false, "alias:^100%2525+up$", []string{"web,3"}},
+		{false, `alias:"^100%25 up$"`, []string{"web,3"}},
+		{false, "name=web%2C3", []string{}},
+		{false, "alias=100%25 up", []string{"web,3"}},
+		{false, "name~^a.b$", []string{"a+b"}},
+		{true, "name:^web%2C3$", []string{}},
+		{true, "alias:^100%25 up$", []string{"web,3"}},
+		{true, `alias:"^100%25 up$"`, []string{"web,3"}},
+		{true, "name=a+b", []string{"a+b"}},
+	}
+
+	defer nrc.SetEncode(false)
+	for _, i := range tests {
+		nrc.SetEncode(i.encode)
+		if got := filterNames(t, i.expr); !reflect.DeepEqual(got, i.want) {
+			t.Errorf("encode %v, %s: got %q, want %q", i.encode, i.expr,
+				got, i.want)
+		}
+	}
+}
+
+func TestFilterErrors(t *testing.T) {
+
+	tests := []struct {
+		expr string
+		pos  int
+	}{
+		{"name", 4},
+		{"name:(web", 4},
+		{`alias="prod`, 6},
+		{"(name=web", 9},
+		{"name=web)", 8},
+		{"=web", 0},
+		{"empty()", 6},
+	}
+
+	for _, i := range tests {
+		_, err := nrc.CompileFilter(i.expr)
+		var fe nrc.FilterError
+		if !errors.As(err, &fe) {
+			t.Errorf("%s: got %v, want a FilterError", i.expr, err)
+			continue
+		}
+		if fe.Pos != i.pos {
+			t.Errorf("%s: error at %d (%v), want %d", i.expr, fe.Pos, err,
+				i.pos)
+		}
+	}
+}
+
+func TestFilterCheck(t *testing.T) {
+
+	f, err := nrc.CompileFilter("name:web,colour=red|size~x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Fields(); !reflect.DeepEqual(got,
+		[]string{"name", "colour", "size"}) {
+		t.Errorf("Fields() = %q", got)
+	}
+
+	err = f.Check([]string{"name", "alias"}, "hosts")
+	var ve nrc.ValidationError
+	if !errors.As(err, &ve) || !reflect.DeepEqual(ve.Fields,
+		[]string{"colour", "size"}) {
+		t.Errorf("Check: got %v, want colour and size unknown", err)
+	}
+}
@@ -0,0 +1,260 @@
+package nrc
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// Formatter writes the records of a table. Fields names the columns in
+// order and each row holds one record's values in that order.
+type Formatter interface {
+	Format(w io.Writer, fields []string, rows [][]string) error
+}
+
+// TextFormatter writes indented field:value blocks, the format of Show.
+// Empty fields are left out unless ShowEmpty is set.
+type TextFormatter struct {
+	ShowEmpty bool
+}
+
+func (f TextFormatter) Format(w io.Writer, fields []string,
+	rows [][]string) error {
+
+	var ind4 = "    " // big indent
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "\n")
+	for _, r := range rows {
+		for i, g := range r {
+			if f.ShowEmpty || g != "" {
+				fmt.Fprintf(bw, "%s%s:%s\n", ind4, fields[i], g)
+			}
+		}
+		fmt.Fprintf(bw, "\n")
+	}
+
+	return bw.Flush()
+}
+
+// JSONFormatter writes a json array of objects, the format of ShowJson.
+// Compact puts everything on one line.
+type JSONFormatter struct {
+	Compact   bool
+	ShowEmpty bool
+}
+
+func (f JSONFormatter) Format(w io.Writer, fields []string,
+	rows [][]string) error {
+
+	var nl = ""   // newline
+	var ind4 = "" // big indent
+	var ind2 = "" // small indent
+
+	if f.Compact == false {
+		nl = "\n"
+		ind4 = "    "
+		ind2 = "  "
+	}
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "[")
+	objcomma := ""
+	for _, r := range rows {
+		comma := ""
+		nli := ""
+		fmt.Fprintf(bw, "%s%s%s{%s", objcomma, nl, ind2, nl)
+		for i, g := range r {
+			if f.ShowEmpty || g != "" {
+				e := new(jsonEncode)
+				e.string(g)
+				fmt.Fprintf(bw, "%s%s%s\"%s\":%s",
+					comma, nli, ind4, fields[i], e.String())
+				comma = ","
+				nli = nl
+			}
+		}
+		fmt.Fprintf(bw, "%s%s}", nl, ind2)
+		objcomma = ","
+	}
+	fmt.Fprintf(bw, "%s]\n", nl)
+
+	return bw.Flush()
+}
+
+// CSVFormatter writes a header line followed by one line per record.
+// Columns, if set, selects and orders the columns written.
+type CSVFormatter struct {
+	Columns  []string
+	NoHeader bool
+}
+
+func (f CSVFormatter) Format(w io.Writer, fields []string,
+	rows [][]string) error {
+
+	if f.Columns != nil {
+		var err error
+		if fields, rows, err = selectColumns(fields, rows,
+			f.Columns); err != nil {
+			return err
+		}
+	}
+
+	cw := csv.NewWriter(w)
+
+	if !f.NoHeader {
+		cw.Write(fields)
+	}
+	for _, r := range rows {
+		cw.Write(r)
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// YAMLFormatter writes a yaml sequence of mappings. Values are always
+// double quoted. Empty fields are left out unless ShowEmpty is set.
+type YAMLFormatter struct {
+	ShowEmpty bool
+}
+
+func (f YAMLFormatter) Format(w io.Writer, fields []string,
+	rows [][]string) error {
+
+	bw := bufio.NewWriter(w)
+
+	if len(rows) == 0 {
+		fmt.Fprintf(bw, "[]\n")
+	}
+	for _, r := range rows {
+		dash := "- "
+		for i, g := range r {
+			if f.ShowEmpty || g != "" {
+				e := new(jsonEncode)
+				e.string(g)
+				fmt.Fprintf(bw, "%s%s: %s\n", dash, fields[i], e.String())
+				dash = "  "
+			}
+		}
+		if dash == "- " {
+			fmt.Fprintf(bw, "- {}\n")
+		}
+	}
+
+	return bw.Flush()
+}
+
+// TableFormatter writes a column aligned table with a header line.
+// Columns that are empty in every record are left out unless ShowEmpty
+// is set, empty cells are shown as a dash and newlines and tabs inside
+// a cell are written escaped. With no records every column is shown so
+// the header is still written.
+type TableFormatter struct {
+	ShowEmpty bool
+}
+
+func (f TableFormatter) Format(w io.Writer, fields []string,
+	rows [][]string) error {
+
+	cols := []int{}
+	for i := range fields {
+		used := f.ShowEmpty || len(rows) == 0
+		for _, r := range rows {
+			if r[i] != "" {
+				used = true
+				break
+			}
+		}
+		if used {
+			cols = append(cols, i)
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	line := func(vals []string) {
+		tab := ""
+		for _, i := range cols {
+			g := cellEscape.Replace(vals[i])
+			if g == "" {
+				g = "-"
+			}
+			fmt.Fprintf(tw, "%s%s", tab, g)
+			tab = "\t"
+		}
+		fmt.Fprintf(tw, "\n")
+	}
+
+	line(fields)
+	for _, r := range rows {
+		line(r)
+	}
+
+	return tw.Flush()
+}
+
+/*
+ * Check that every column is one of options
+ */
+func checkColumns(options []string, table string, columns []string) error {
+
+	valid := make(map[string]bool)
+	for _, i := range options {
+		valid[i] = true
+	}
+
+	unknown := []string{}
+	for _, i := range columns {
+		if !valid[i] {
+			unknown = append(unknown, i)
+		}
+	}
+	if len(unknown) > 0 {
+		txt := fmt.Sprintf("Unknown column(s) for %s: %s.", table,
+			strings.Join(unknown, ", "))
+		return ValidationError{table, unknown, txt}
+	}
+
+	return nil
+}
+
+var cellEscape = strings.NewReplacer("\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+/*
+ * Reduce fields and rows to the named columns, in the order given
+ */
+func selectColumns(fields []string, rows [][]string,
+	columns []string) ([]string, [][]string, error) {
+
+	index := make(map[string]int)
+	for i, j := range fields {
+		index[j] = i
+	}
+
+	pick := []int{}
+	for _, j := range columns {
+		i, ok := index[j]
+		if !ok {
+			txt := fmt.Sprintf("Unknown column '%s'.", j)
+			return nil, nil, ValidationError{Fields: []string{j},
+				Message: txt}
+		}
+		pick = append(pick, i)
+	}
+
+	newRows := make([][]string, len(rows))
+	for n, r := range rows {
+		newRows[n] = make([]string, len(pick))
+		for k, i := range pick {
+			newRows[n][k] = r[i]
+		}
+	}
+
+	return append([]string{}, columns...), newRows, nil
+}
@@ -0,0 +1,348 @@
+package nrc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	nrc "github.com/mclarkson/nagrestconf-golib"
+	"github.com/mclarkson/nagrestconf-golib/nrctest"
+)
+
+/*
+ * Format rows with f and decode the output with encoding/json
+ */
+func formatJSON(t *testing.T, f nrc.JSONFormatter, fields []string,
+	rows [][]string) []map[string]string {
+
+	var buf bytes.Buffer
+	if err := f.Format(&buf, fields, rows); err != nil {
+		t.Fatal(err)
+	}
+
+	out := []map[string]string{}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("invalid json %q: %v", buf.String(), err)
+	}
+
+	return out
+}
+
+// What a string decodes to: each byte of invalid UTF-8 becomes U+FFFD
+func valid(s string) string {
+	return string([]rune(s))
+}
+
+func TestJSONFormatterRoundTrip(t *testing.T) {
+
+	values := []string{
+		"",
+		"plain",
+		`say "hello"`,
+		`C:\nagios\bin`,
+		`\"`,
+		"tab\there",
+		"line\nbreak\r\n",
+		"\x00\x01\x1f\x7f",
+		"<b>&amp;</b>",
+		"\u2028\u2029",
+		"日本語 é",
+		"bad \xff\xfe utf-8",
+		"\xc3",
+		"check_http!-u /a%20b?x=1&y=2+3",
+	}
+	fields := []string{"name", "alias", "command"}
+
+	for _, f := range []nrc.JSONFormatter{{}, {Compact: true},
+		{ShowEmpty: true}, {Compact: true, ShowEmpty: true}} {
+
+		rows := [][]string{}
+		for _, v := range values {
+			rows = append(rows, []string{"web1", v, "x" + v})
+		}
+
+		got := formatJSON(t, f, fields, rows)
+		if len(got) != len(rows) {
+			t.Fatalf("%+v: got %d objects, want %d", f, len(got), len(rows))
+		}
+		for n, v := range values {
+			want := map[string]string{"name": "web1",
+				"command": valid("x" + v)}
+			if v != "" || f.ShowEmpty {
+				want["alias"] = valid(v)
+			}
+			if len(got[n]) != len(want) || got[n]["alias"] != want["alias"] ||
+				got[n]["command"] != want["command"] {
+				t.Errorf("%+v: %q came back as %q", f, v, got[n])
+			}
+		}
+	}
+}
+
+func TestJSONFormatterRandom(t *testing.T) {
+
+	fields := []string{"name", "alias"}
+
+	check := func(a, b []byte) bool {
+		row := []string{string(a), string(b)}
+		got := formatJSON(t, nrc.JSONFormatter{ShowEmpty: true}, fields,
+			[][]string{row})
+		return len(got) == 1 && got[0]["name"] == valid(row[0]) &&
+			got[0]["alias"] == valid(row[1])
+	}
+
+	if err := quick.Check(check, &quick.Config{MaxCount: 5000}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestJSONFormatterEmpty(t *testing.T) {
+
+	for _, f := range []nrc.JSONFormatter{{}, {Compact: true}} {
+		if got := formatJSON(t, f, []string{"name"}, nil); len(got) != 0 {
+			t.Errorf("%+v: got %v for no rows", f, got)
+		}
+	}
+}
+
+func TestCSVFormatter(t *testing.T) {
+
+	fields := []string{"name", "alias", "command"}
+	rows := [][]string{
+		{"web1", `say "hi", then go`, "check_http!-u /\nnext"},
+		{"web2", "", " leading space"},
+	}
+
+	tests := []struct {
+		f    nrc.CSVFormatter
+		want [][]string
+	}{
+		{nrc.CSVFormatter{}, append([][]string{fields}, rows...)},
+		{nrc.CSVFormatter{NoHeader: true}, rows},
+		{nrc.CSVFormatter{Columns: []string{"command", "name"}}, [][]string{
+			{"command", "name"},
+			{"check_http!-u /\nnext", "web1"},
+			{" leading space", "web2"},
+		}},
+	}
+
+	for _, i := range tests {
+		var buf bytes.Buffer
+		if err := i.f.Format(&buf, fields, rows); err != nil {
+			t.Fatalf("%+v: %v", i.f, err)
+		}
+		got, err := csv.NewReader(&buf).ReadAll()
+		if err != nil {
+			t.Fatalf("%+v: invalid csv: %v", i.f, err)
+		}
+		if !reflect.DeepEqual(got, i.want) {
+			t.Errorf("%+v: read back %q, want %q", i.f, got, i.want)
+		}
+	}
+
+	var buf bytes.Buffer
+	err := nrc.CSVFormatter{Columns: []string{"name", "colour"}}.Format(&buf,
+		fields, rows)
+	var ve nrc.ValidationError
+	if !errors.As(err, &ve) || !reflect.DeepEqual(ve.Fields,
+		[]string{"colour"}) {
+		t.Errorf("unknown column: got %v, want a ValidationError", err)
+	}
+}
+
+/*
+ * Read back YAMLFormatter output. It writes a sequence of block mappings
+ * with double quoted values, "- {}" for an empty mapping and "[]" for an
+ * empty sequence, and its quoting is also valid json.
+ */
+func readYAML(t *testing.T, text string) []map[string]string {
+
+	out := []map[string]string{}
+	if text == "[]\n" {
+		return out
+	}
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if line == "" {
+			continue
+		}
+		if !strings.HasSuffix(line, "\n") {
+			t.Fatalf("unterminated line %q", line)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "- {}" {
+			out = append(out, map[string]string{})
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "- "):
+			out = append(out, map[string]string{})
+		case strings.HasPrefix(line, "  ") && len(out) > 0:
+		default:
+			t.Fatalf("unexpected line %q", line)
+		}
+		kv := strings.SplitN(line[2:], ": ", 2)
+		var v string
+		if len(kv) != 2 || json.Unmarshal([]byte(kv[1]), &v) != nil {
+			t.Fatalf("bad mapping entry %q", line)
+		}
+		out[len(out)-1][kv[0]] = v
+	}
+
+	return out
+}
+
+func TestYAMLFormatter(t *testing.T) {
+
+	fields := []string{"name", "alias", "notes"}
+	rows := [][]string{
+		{"web1", "a: b # not a comment", "line\nbreak\t\"q\" \\"},
+		{"", "", ""},
+		{"- dash", "", "'single'"},
+	}
+
+	tests := []struct {
+		f    nrc.YAMLFormatter
+		want []map[string]string
+	}{
+		{nrc.YAMLFormatter{}, []map[string]string{
+			{"name": "web1", "alias": "a: b # not a comment",
+				"notes": "line\nbreak\t\"q\" \\"},
+			{},
+			{"name": "- dash", "notes": "'single'"},
+		}},
+		{nrc.YAMLFormatter{ShowEmpty: true}, []map[string]string{
+			{"name": "web1", "alias": "a: b # not a comment",
+				"notes": "line\nbreak\t\"q\" \\"},
+			{"name": "", "alias": "", "notes": ""},
+			{"name": "- dash", "alias": "", "notes": "'single'"},
+		}},
+	}
+
+	for _, i := range tests {
+		var buf bytes.Buffer
+		if err := i.f.Format(&buf, fields, rows); err != nil {
+			t.Fatal(err)
+		}
+		if got := readYAML(t, buf.String()); !reflect.DeepEqual(got,
+			i.want) {
+			t.Errorf("%+v: read back %q from\n%s", i.f, got, buf.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := (nrc.YAMLFormatter{}).Format(&buf, fields, nil); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "[]\n" {
+		t.Errorf("no rows written as %q, want an empty sequence", buf.String())
+	}
+}
+
+func TestTableFormatter(t *testing.T) {
+
+	fields := []string{"name", "alias", "notes", "command"}
+	rows := [][]string{
+		{"web1", "", "", "check\tping"},
+		{"database2", "db", "", "a\nb\r"},
+	}
+
+	tests := []struct {
+		f    nrc.TableFormatter
+		rows [][]string
+		want string
+	}{
+		{nrc.TableFormatter{}, rows, "" +
+			"name       alias  command\n" +
+			"web1       -      check\\tping\n" +
+			"database2  db     a\\nb\\r\n"},
+		{nrc.TableFormatter{ShowEmpty: true}, rows, "" +
+			"name       alias  notes  command\n" +
+			"web1       -      -      check\\tping\n" +
+			"database2  db     -      a\\nb\\r\n"},
+		{nrc.TableFormatter{}, nil, "name  alias  notes  command\n"},
+	}
+
+	for _, i := range tests {
+		var buf bytes.Buffer
+		if err := i.f.Format(&buf, fields, i.rows); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != i.want {
+			t.Errorf("%+v, %d rows: got\n%s\nwant\n%s", i.f, len(i.rows),
+				buf.String(), i.want)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+
+	s := nrctest.NewServer()
+	defer s.Close()
+	s.Load("local", "hosts",
+		map[string]string{"name": "web1", "alias": "Web", "ipaddress": "10.0.0.1",
+			"template": "hsttmpl-local"})
+
+	h := s.NrcClient("local").Hosts()
+	if err := h.GetContext(context.Background(), "", "rest/show/hosts", "",
+		nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Select("ipaddress", "name"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The selected order is kept by every format
+	tests := []struct {
+		f    nrc.Formatter
+		want string
+	}{
+		{nrc.TextFormatter{}, "\n    ipaddress:10.0.0.1\n    name:web1\n\n"},
+		{nrc.JSONFormatter{Compact: true},
+			`[{"ipaddress":"10.0.0.1","name":"web1"}]` + "\n"},
+		{nrc.CSVFormatter{}, "ipaddress,name\n10.0.0.1,web1\n"},
+		{nrc.YAMLFormatter{}, "- ipaddress: \"10.0.0.1\"\n  name: \"web1\"\n"},
+		{nrc.TableFormatter{}, "ipaddress  name\n10.0.0.1   web1\n"},
+	}
+	for _, i := range tests {
+		var buf bytes.Buffer
+		if err := h.Write(&buf, i.f, ""); err != nil {
+			t.Fatalf("%T: %v", i.f, err)
+		}
+		if buf.String() != i.want {
+			t.Errorf("%T: got %q, want %q", i.f, buf.String(), i.want)
+		}
+	}
+
+	// Unknown columns are refused and the selection kept
+	err := h.Select("name", "colour", "size")
+	var ve nrc.ValidationError
+	if !errors.As(err, &ve) || !reflect.DeepEqual(ve.Fields,
+		[]string{"colour", "size"}) {
+		t.Errorf("unknown columns: got %v, want a ValidationError", err)
+	}
+	var buf bytes.Buffer
+	if err := h.Write(&buf, nrc.CSVFormatter{NoHeader: true}, ""); err != nil ||
+		buf.String() != "10.0.0.1,web1\n" {
+		t.Errorf("after a bad Select wrote %q, %v", buf.String(), err)
+	}
+
+	// No columns selects every field again
+	if err := h.Select(); err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := h.Write(&buf, nrc.CSVFormatter{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	header := strings.SplitN(buf.String(), "\n", 2)[0]
+	hosts, _ := nrc.Table("hosts")
+	if want := strings.Join(hosts.Fields, ","); header != want {
+		t.Errorf("after Select() header %q, want %q", header, want)
+	}
+}
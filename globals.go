@@ -1,7 +1,9 @@
 package nrc
 
 import (
+	"context"
 	"fmt"
+	"io"
 )
 
 const (
@@ -9,6 +11,8 @@ const (
 	ERROR   = 1
 )
 
+// HttpError carries a plain message. Request failures use the types in
+// errors.go instead.
 type HttpError struct {
 	details string
 }
@@ -26,3 +30,19 @@ type NrcQuery interface {
 	Get(string, string, string, []string) error
 	Post(string, string, string, []string) error
 }
+
+// NrcQueryContext is implemented by every query. The context variants
+// bound each request by the context's deadline and cancel it along with
+// the context.
+type NrcQueryContext interface {
+	NrcQuery
+	GetContext(context.Context, string, string, string, []string) error
+	PostContext(context.Context, string, string, string, []string) error
+}
+
+// NrcWriter is implemented by every query. Show and ShowJson write to
+// os.Stdout through these.
+type NrcWriter interface {
+	WriteText(io.Writer, bool, string) error
+	WriteJSON(io.Writer, bool, bool, string) error
+}
@@ -0,0 +1,3 @@
+module github.com/mclarkson/nagrestconf-golib
+
+go 1.17
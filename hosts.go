@@ -1,91 +1,380 @@
+// Code generated by internal/gen from schema.json; DO NOT EDIT.
+
 package nrc
 
 import (
-	"bytes"
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"reflect"
-	"regexp"
+	"io"
+	"os"
 	"sort"
-	"strings"
 )
 
-type host struct {
-	name                  string
-	alias                 string
-	ipaddress             string
-	template              string
-	hostgroup             string
-	contact               string
-	contactgroups         string
-	activechecks          string
-	servicesets           string
-	disable               string
-	displayname           string
-	parents               string
-	command               string
-	initialstate          string
-	maxcheckattempts      string
-	checkinterval         string
-	retryinterval         string
-	passivechecks         string
-	checkperiod           string
-	obsessoverhost        string
-	checkfreshness        string
-	freshnessthresh       string
-	eventhandler          string
-	eventhandlerenabled   string
-	lowflapthresh         string
-	highflapthresh        string
-	flapdetectionenabled  string
-	flapdetectionoptions  string
-	processperfdata       string
-	retainstatusinfo      string
-	retainnonstatusinfo   string
-	notifinterval         string
-	firstnotifdelay       string
-	notifperiod           string
-	notifopts             string
-	notifications_enabled string
-	stalkingoptions       string
-	notes                 string
-	notes_url             string
-	icon_image            string
-	icon_image_alt        string
-	vrml_image            string
-	statusmap_image       string
-	coords2d              string
-	coords3d              string
-	action_url            string
-	customvars            string
+// Host is one record of the nagrestconf hosts table.
+type Host struct {
+	Name                 string `json:"name,omitempty"`
+	Alias                string `json:"alias,omitempty"`
+	Ipaddress            string `json:"ipaddress,omitempty"`
+	Template             string `json:"template,omitempty"`
+	Hostgroup            string `json:"hostgroup,omitempty"`
+	Contact              string `json:"contact,omitempty"`
+	Contactgroups        string `json:"contactgroups,omitempty"`
+	Activechecks         string `json:"activechecks,omitempty"`
+	Servicesets          string `json:"servicesets,omitempty"`
+	Disable              string `json:"disable,omitempty"`
+	Displayname          string `json:"displayname,omitempty"`
+	Parents              string `json:"parents,omitempty"`
+	Command              string `json:"command,omitempty"`
+	Initialstate         string `json:"initialstate,omitempty"`
+	Maxcheckattempts     string `json:"maxcheckattempts,omitempty"`
+	Checkinterval        string `json:"checkinterval,omitempty"`
+	Retryinterval        string `json:"retryinterval,omitempty"`
+	Passivechecks        string `json:"passivechecks,omitempty"`
+	Checkperiod          string `json:"checkperiod,omitempty"`
+	Obsessoverhost       string `json:"obsessoverhost,omitempty"`
+	Checkfreshness       string `json:"checkfreshness,omitempty"`
+	Freshnessthresh      string `json:"freshnessthresh,omitempty"`
+	Eventhandler         string `json:"eventhandler,omitempty"`
+	Eventhandlerenabled  string `json:"eventhandlerenabled,omitempty"`
+	Lowflapthresh        string `json:"lowflapthresh,omitempty"`
+	Highflapthresh       string `json:"highflapthresh,omitempty"`
+	Flapdetectionenabled string `json:"flapdetectionenabled,omitempty"`
+	Flapdetectionoptions string `json:"flapdetectionoptions,omitempty"`
+	Processperfdata      string `json:"processperfdata,omitempty"`
+	Retainstatusinfo     string `json:"retainstatusinfo,omitempty"`
+	Retainnonstatusinfo  string `json:"retainnonstatusinfo,omitempty"`
+	Notifinterval        string `json:"notifinterval,omitempty"`
+	Firstnotifdelay      string `json:"firstnotifdelay,omitempty"`
+	Notifperiod          string `json:"notifperiod,omitempty"`
+	Notifopts            string `json:"notifopts,omitempty"`
+	NotificationsEnabled string `json:"notifications_enabled,omitempty"`
+	Stalkingoptions      string `json:"stalkingoptions,omitempty"`
+	Notes                string `json:"notes,omitempty"`
+	NotesUrl             string `json:"notes_url,omitempty"`
+	IconImage            string `json:"icon_image,omitempty"`
+	IconImageAlt         string `json:"icon_image_alt,omitempty"`
+	VrmlImage            string `json:"vrml_image,omitempty"`
+	StatusmapImage       string `json:"statusmap_image,omitempty"`
+	Coords2d             string `json:"coords2d,omitempty"`
+	Coords3d             string `json:"coords3d,omitempty"`
+	ActionUrl            string `json:"action_url,omitempty"`
+	Customvars           string `json:"customvars,omitempty"`
+}
+
+func (r Host) TableName() string {
+	return "hosts"
+}
+
+/*
+ * Return the value of the named field and whether the table has it
+ */
+func (r Host) Field(name string) (string, bool) {
+	switch name {
+	case "name":
+		return r.Name, true
+	case "alias":
+		return r.Alias, true
+	case "ipaddress":
+		return r.Ipaddress, true
+	case "template":
+		return r.Template, true
+	case "hostgroup":
+		return r.Hostgroup, true
+	case "contact":
+		return r.Contact, true
+	case "contactgroups":
+		return r.Contactgroups, true
+	case "activechecks":
+		return r.Activechecks, true
+	case "servicesets":
+		return r.Servicesets, true
+	case "disable":
+		return r.Disable, true
+	case "displayname":
+		return r.Displayname, true
+	case "parents":
+		return r.Parents, true
+	case "command":
+		return r.Command, true
+	case "initialstate":
+		return r.Initialstate, true
+	case "maxcheckattempts":
+		return r.Maxcheckattempts, true
+	case "checkinterval":
+		return r.Checkinterval, true
+	case "retryinterval":
+		return r.Retryinterval, true
+	case "passivechecks":
+		return r.Passivechecks, true
+	case "checkperiod":
+		return r.Checkperiod, true
+	case "obsessoverhost":
+		return r.Obsessoverhost, true
+	case "checkfreshness":
+		return r.Checkfreshness, true
+	case "freshnessthresh":
+		return r.Freshnessthresh, true
+	case "eventhandler":
+		return r.Eventhandler, true
+	case "eventhandlerenabled":
+		return r.Eventhandlerenabled, true
+	case "lowflapthresh":
+		return r.Lowflapthresh, true
+	case "highflapthresh":
+		return r.Highflapthresh, true
+	case "flapdetectionenabled":
+		return r.Flapdetectionenabled, true
+	case "flapdetectionoptions":
+		return r.Flapdetectionoptions, true
+	case "processperfdata":
+		return r.Processperfdata, true
+	case "retainstatusinfo":
+		return r.Retainstatusinfo, true
+	case "retainnonstatusinfo":
+		return r.Retainnonstatusinfo, true
+	case "notifinterval":
+		return r.Notifinterval, true
+	case "firstnotifdelay":
+		return r.Firstnotifdelay, true
+	case "notifperiod":
+		return r.Notifperiod, true
+	case "notifopts":
+		return r.Notifopts, true
+	case "notifications_enabled":
+		return r.NotificationsEnabled, true
+	case "stalkingoptions":
+		return r.Stalkingoptions, true
+	case "notes":
+		return r.Notes, true
+	case "notes_url":
+		return r.NotesUrl, true
+	case "icon_image":
+		return r.IconImage, true
+	case "icon_image_alt":
+		return r.IconImageAlt, true
+	case "vrml_image":
+		return r.VrmlImage, true
+	case "statusmap_image":
+		return r.StatusmapImage, true
+	case "coords2d":
+		return r.Coords2d, true
+	case "coords3d":
+		return r.Coords3d, true
+	case "action_url":
+		return r.ActionUrl, true
+	case "customvars":
+		return r.Customvars, true
+	}
+	return "", false
+}
+
+/*
+ * Set the named field, returning false if the table has no such field
+ */
+func (r *Host) SetField(name, value string) bool {
+	switch name {
+	case "name":
+		r.Name = value
+	case "alias":
+		r.Alias = value
+	case "ipaddress":
+		r.Ipaddress = value
+	case "template":
+		r.Template = value
+	case "hostgroup":
+		r.Hostgroup = value
+	case "contact":
+		r.Contact = value
+	case "contactgroups":
+		r.Contactgroups = value
+	case "activechecks":
+		r.Activechecks = value
+	case "servicesets":
+		r.Servicesets = value
+	case "disable":
+		r.Disable = value
+	case "displayname":
+		r.Displayname = value
+	case "parents":
+		r.Parents = value
+	case "command":
+		r.Command = value
+	case "initialstate":
+		r.Initialstate = value
+	case "maxcheckattempts":
+		r.Maxcheckattempts = value
+	case "checkinterval":
+		r.Checkinterval = value
+	case "retryinterval":
+		r.Retryinterval = value
+	case "passivechecks":
+		r.Passivechecks = value
+	case "checkperiod":
+		r.Checkperiod = value
+	case "obsessoverhost":
+		r.Obsessoverhost = value
+	case "checkfreshness":
+		r.Checkfreshness = value
+	case "freshnessthresh":
+		r.Freshnessthresh = value
+	case "eventhandler":
+		r.Eventhandler = value
+	case "eventhandlerenabled":
+		r.Eventhandlerenabled = value
+	case "lowflapthresh":
+		r.Lowflapthresh = value
+	case "highflapthresh":
+		r.Highflapthresh = value
+	case "flapdetectionenabled":
+		r.Flapdetectionenabled = value
+	case "flapdetectionoptions":
+		r.Flapdetectionoptions = value
+	case "processperfdata":
+		r.Processperfdata = value
+	case "retainstatusinfo":
+		r.Retainstatusinfo = value
+	case "retainnonstatusinfo":
+		r.Retainnonstatusinfo = value
+	case "notifinterval":
+		r.Notifinterval = value
+	case "firstnotifdelay":
+		r.Firstnotifdelay = value
+	case "notifperiod":
+		r.Notifperiod = value
+	case "notifopts":
+		r.Notifopts = value
+	case "notifications_enabled":
+		r.NotificationsEnabled = value
+	case "stalkingoptions":
+		r.Stalkingoptions = value
+	case "notes":
+		r.Notes = value
+	case "notes_url":
+		r.NotesUrl = value
+	case "icon_image":
+		r.IconImage = value
+	case "icon_image_alt":
+		r.IconImageAlt = value
+	case "vrml_image":
+		r.VrmlImage = value
+	case "statusmap_image":
+		r.StatusmapImage = value
+	case "coords2d":
+		r.Coords2d = value
+	case "coords3d":
+		r.Coords3d = value
+	case "action_url":
+		r.ActionUrl = value
+	case "customvars":
+		r.Customvars = value
+	default:
+		return false
+	}
+	return true
+}
+
+/*
+ * Return the field values in table column order
+ */
+func (r Host) Values() []string {
+	return []string{
+		r.Name,
+		r.Alias,
+		r.Ipaddress,
+		r.Template,
+		r.Hostgroup,
+		r.Contact,
+		r.Contactgroups,
+		r.Activechecks,
+		r.Servicesets,
+		r.Disable,
+		r.Displayname,
+		r.Parents,
+		r.Command,
+		r.Initialstate,
+		r.Maxcheckattempts,
+		r.Checkinterval,
+		r.Retryinterval,
+		r.Passivechecks,
+		r.Checkperiod,
+		r.Obsessoverhost,
+		r.Checkfreshness,
+		r.Freshnessthresh,
+		r.Eventhandler,
+		r.Eventhandlerenabled,
+		r.Lowflapthresh,
+		r.Highflapthresh,
+		r.Flapdetectionenabled,
+		r.Flapdetectionoptions,
+		r.Processperfdata,
+		r.Retainstatusinfo,
+		r.Retainnonstatusinfo,
+		r.Notifinterval,
+		r.Firstnotifdelay,
+		r.Notifperiod,
+		r.Notifopts,
+		r.NotificationsEnabled,
+		r.Stalkingoptions,
+		r.Notes,
+		r.NotesUrl,
+		r.IconImage,
+		r.IconImageAlt,
+		r.VrmlImage,
+		r.StatusmapImage,
+		r.Coords2d,
+		r.Coords3d,
+		r.ActionUrl,
+		r.Customvars,
+	}
 }
 
 type Hosts struct {
-	hosts    []host
-	username string
-	password string
+	hosts   []Host
+	client  *Client
+	columns []string
+	order   []sortKey
 }
 
 func (h Hosts) RequiredOptions() []string {
-	return []string{"name", "alias", "ipaddress", "template"}
+	t, _ := Table("hosts")
+	return t.Required
 }
 
 func (h *Hosts) Options() (arr []string) {
 
-	n := reflect.TypeOf(h.hosts).Elem().NumField()
-	for i := 0; i < n; i++ {
-		f := reflect.TypeOf(h.hosts).Elem().Field(i)
-		arr = append(arr, f.Name)
-	}
+	t, _ := Table("hosts")
+	arr = t.Fields
 
 	sort.Strings(arr)
 
 	return arr
 }
 
+/*
+ * Return a copy of the records fetched by Get, in the order set by
+ * SortBy
+ */
+func (h Hosts) Records() []Host {
+	return h.sorted()
+}
+
+/*
+ * Return a copy of the records, sorted if SortBy was used
+ */
+func (h Hosts) sorted() []Host {
+
+	r := append([]Host(nil), h.hosts...)
+
+	if h.order != nil {
+		sort.SliceStable(r, func(i, j int) bool {
+			return compareRecords(h.order, r[i], r[j]) < 0
+		})
+	}
+
+	return r
+}
+
 func (h Hosts) OptionsJson() (s string) {
 
 	f := h.Options()
@@ -101,368 +390,290 @@ func (h Hosts) OptionsJson() (s string) {
 	return s
 }
 
-func (h *Hosts) filterHosts(filter string) {
+/*
+ * Keep only the records that pass the filter expression. Malformed
+ * filters and unknown field names are errors.
+ */
+func (h *Hosts) filterHosts(filter string) error {
 
-	f := NewFilter(filter)
+	f, err := CompileFilter(filter)
+	if err != nil {
+		return err
+	}
+	if err := f.Check(h.Options(), "hosts"); err != nil {
+		return err
+	}
 
-	newh := []host{}
+	newh := []Host{}
 
 	for _, k := range h.hosts {
-		t := &k // Must be a pointer or interface for reflection
-		foundCount := 0
-		for i := 0; i < len(f.names); i++ {
-			_, found := reflect.TypeOf(t).Elem().FieldByName(f.names[i])
-			if found == true {
-				val := reflect.ValueOf(t).Elem().FieldByName(f.names[i])
-				userRegx, _ := UrlDecodeForce(f.regex[i])
-				regex := regexp.MustCompile(userRegx)
-				if regex.MatchString(val.String()) {
-					foundCount += 1
-					continue
-				}
-				/*
-					val := reflect.ValueOf(t).Elem().FieldByName(f.names[i])
-					if val.String() == f.regex[i] {
-						foundCount += 1
-						continue
-					}
-				*/
-			}
-		}
-		if foundCount == len(f.names) {
+		if f.Match(k) {
 			newh = append(newh, k)
 		}
 	}
 
 	// Replace the list we got with this filtered list
 	h.hosts = newh
+
+	return nil
 }
 
 func (h Hosts) Show(brief bool, filter string) {
-
-	if filter != "" {
-		h.filterHosts(filter)
+	if err := h.WriteText(os.Stdout, brief, filter); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
 	}
+}
 
-	var ind4 = "    " // big indent
-
-	fmt.Printf("\n")
-	for _, r := range h.hosts {
-		t := &r // Reflection is only allowed on ptr or interface
-		n := reflect.TypeOf(t).Elem().NumField()
-		for i := 0; i < n; i++ {
-			f := reflect.TypeOf(t).Elem().Field(i)
-			g := reflect.ValueOf(t).Elem().Field(i)
-			if brief == true || (brief == false && g.String() != "") {
-				fmt.Printf("%s%s:%s\n",
-					ind4, f.Name, g)
-			}
-		}
-		fmt.Printf("\n")
-	}
+/*
+ * Write the records as indented field:value blocks
+ */
+func (h Hosts) WriteText(w io.Writer, brief bool, filter string) error {
+	return h.Write(w, TextFormatter{ShowEmpty: brief}, filter)
 }
 
 func (h Hosts) ShowJson(newline, brief bool, filter string) {
+	if err := h.WriteJSON(os.Stdout, newline, brief, filter); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+	}
+}
+
+/*
+ * Write the records as a json array of objects. Newline set means
+ * everything goes on one line.
+ */
+func (h Hosts) WriteJSON(w io.Writer, newline, brief bool,
+	filter string) error {
+	return h.Write(w, JSONFormatter{Compact: newline, ShowEmpty: brief}, filter)
+}
+
+/*
+ * Write the records, after filtering and sorting, using formatter f
+ */
+func (h Hosts) Write(w io.Writer, f Formatter, filter string) error {
 
 	if filter != "" {
-		h.filterHosts(filter)
+		if err := h.filterHosts(filter); err != nil {
+			return err
+		}
 	}
 
-	var nl = ""   // newline
-	var ind4 = "" // big indent
-	var ind2 = "" // small indent
+	t, _ := Table("hosts")
 
-	if newline == false {
-		nl = "\n"
-		ind4 = "    "
-		ind2 = "  "
+	rows := make([][]string, 0, len(h.hosts))
+	for _, r := range h.sorted() {
+		rows = append(rows, r.Values())
 	}
 
-	fmt.Printf("[")
-	objcomma := ""
-	for _, r := range h.hosts {
-		comma := ""
-		nli := ""
-		fmt.Printf("%s%s%s{%s", objcomma, nl, ind2, nl)
-		t := &r // Reflection is only allowed on ptr or interface
-		n := reflect.TypeOf(t).Elem().NumField()
-		for i := 0; i < n; i++ {
-			f := reflect.TypeOf(t).Elem().Field(i)
-			g := reflect.ValueOf(t).Elem().Field(i)
-			if brief == true || (brief == false && g.String() != "") {
-				fmt.Printf("%s%s%s\"%s\":\"%s\"",
-					comma, nli, ind4, f.Name, g)
-				comma = ","
-				nli = nl
-			}
+	if h.columns != nil {
+		fields, rows, err := selectColumns(t.Fields, rows, h.columns)
+		if err != nil {
+			return err
 		}
-		fmt.Printf("%s%s}", nl, ind2)
-		objcomma = ","
+		return f.Format(w, fields, rows)
 	}
-	fmt.Printf("%s]\n", nl)
-}
 
-func NewNrcHosts(username, password string) *Hosts {
-	h := &Hosts{}
-	h.username = username
-	h.password = password
-	return h
+	return f.Format(w, t.Fields, rows)
 }
 
 /*
- * Send HTTP GET request
+ * Choose the fields, and their order, used by every output format.
+ * No columns means all fields again.
  */
-func (h *Hosts) Get(url, endpoint, folder string, data []string) (e error) {
+func (h *Hosts) Select(columns ...string) error {
 
-	// accept bad certs
-	tr := &http.Transport{
-		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
-		DisableKeepAlives: true,
+	if len(columns) == 0 {
+		h.columns = nil
+		return nil
 	}
-	client := &http.Client{Transport: tr}
-	// Not available in Go<1.3
-	//client.Timeout = 8 * 1e9
 
-	for strings.HasSuffix(url, "/") {
-		url = strings.TrimSuffix(url, "/")
-	}
-	for strings.HasSuffix(endpoint, "/") {
-		url = strings.TrimSuffix(endpoint, "/")
+	if err := checkColumns(h.Options(), "hosts", columns); err != nil {
+		return err
 	}
+	h.columns = append([]string{}, columns...)
 
-	// Construct url, http://1.2.3.4/rest/show/hosts?json={"folder":"local",...}
-	fullUrl := url + "/" + endpoint + "?json={\"folder\":\"" + folder + "\""
-	dataStr, err := FormatData(data, "services")
-	if err != nil {
-		txt := fmt.Sprintf("Could not format data. Check the '-d' option.")
-		return HttpError{txt}
-	}
-	if dataStr != "" {
-		fullUrl += "," + dataStr
-	}
-	fullUrl += "}"
+	return nil
+}
 
-	//fmt.Printf("URL=%s\n", fullUrl)
+/*
+ * Set the order of the records returned by Records and written by every
+ * output format. Records are sorted on the first field, then the second
+ * and so on, with runs of digits compared as numbers so web9 comes
+ * before web10. Prefix a field with '-' to sort it descending. No fields
+ * means the order the server returned.
+ */
+func (h *Hosts) SortBy(fields ...string) error {
 
-	//fmt.Printf("%s\n", url+"/"+endpoint)
-	//resp, err := client.Get(fullUrl)
-	req, err := http.NewRequest("GET", fullUrl, nil)
-	if len(h.username) > 0 {
-		req.SetBasicAuth(h.username, h.password)
+	if len(fields) == 0 {
+		h.order = nil
+		return nil
 	}
-	resp, err := client.Do(req)
+
+	order, err := parseSort(h.Options(), "hosts", fields)
 	if err != nil {
-		txt := fmt.Sprintf("Could not send REST request ('%s').", err.Error())
-		return HttpError{txt}
+		return err
 	}
+	h.order = order
 
-	defer resp.Body.Close()
+	return nil
+}
 
-	var body []byte
-	if b, err := ioutil.ReadAll(resp.Body); err != nil {
-		txt := fmt.Sprintf("Error reading Body ('%s').", err.Error())
-		return HttpError{txt}
-	} else {
-		body = b
-	}
+// NewNrcHosts returns a query that skips server certificate
+// verification.
+//
+// Deprecated: Use NewClient, with SetTLS for a CA bundle or pinned
+// fingerprint, and its Hosts method.
+func NewNrcHosts(username, password string) *Hosts {
+	return newInsecureClient(username, password).Hosts()
+}
 
-	if resp.StatusCode != 200 {
+/*
+ * Create a Hosts query that sends its requests through c
+ */
+func (c *Client) Hosts() *Hosts {
+	h := &Hosts{}
+	h.client = c
+	return h
+}
 
-		response, _ := UrlDecode(string(body))
-		txt := fmt.Sprintf("Status (%d): %s", resp.StatusCode, response)
-		return HttpError{txt}
+/*
+ * Send HTTP GET request
+ */
+func (h *Hosts) Get(url, endpoint, folder string, data []string) (e error) {
+	return h.GetContext(context.Background(), url, endpoint, folder, data)
+}
 
-	} else {
+func (h *Hosts) GetContext(ctx context.Context, url, endpoint,
+	folder string, data []string) (e error) {
 
-		var generic interface{}
-		if err := json.Unmarshal(body, &generic); err != nil {
-			txt := fmt.Sprintf("Status (%d) Error decoding JSON (%s).",
-				resp.StatusCode, err.Error())
-			return HttpError{txt}
-		}
-		genericReply := generic.([]interface{})
-
-		//fmt.Printf("%s\n", body)
-
-		for _, j := range genericReply {
-			host := host{}
-			for _, j2 := range j.([]interface{}) {
-				content := j2.(map[string]interface{})
-				for k, v := range content {
-					switch k {
-					case "name":
-						host.name = v.(string)
-					case "alias":
-						host.alias, _ = UrlDecode(v.(string))
-					case "ipaddress":
-						host.ipaddress = v.(string)
-					case "template":
-						host.template = v.(string)
-					case "hostgroup":
-						host.hostgroup = v.(string)
-					case "contact":
-						host.contact = v.(string)
-					case "contactgroups":
-						host.contactgroups = v.(string)
-					case "activechecks":
-						host.activechecks = v.(string)
-					case "servicesets":
-						host.servicesets = v.(string)
-					case "disable":
-						host.disable = v.(string)
-					case "displayname":
-						host.displayname = v.(string)
-					case "parents":
-						host.parents = v.(string)
-					case "command":
-						host.command, _ = UrlDecode(v.(string))
-					case "initialstate":
-						host.initialstate = v.(string)
-					case "maxcheckattempts":
-						host.maxcheckattempts = v.(string)
-					case "checkinterval":
-						host.checkinterval = v.(string)
-					case "retryinterval":
-						host.retryinterval = v.(string)
-					case "passivechecks":
-						host.passivechecks = v.(string)
-					case "checkperiod":
-						host.checkperiod = v.(string)
-					case "obsessoverhost":
-						host.obsessoverhost = v.(string)
-					case "checkfreshness":
-						host.checkfreshness = v.(string)
-					case "freshnessthresh":
-						host.freshnessthresh = v.(string)
-					case "eventhandler":
-						host.eventhandler = v.(string)
-					case "eventhandlerenabled":
-						host.eventhandlerenabled = v.(string)
-					case "lowflapthresh":
-						host.lowflapthresh = v.(string)
-					case "highflapthresh":
-						host.highflapthresh = v.(string)
-					case "flapdetectionenabled":
-						host.flapdetectionenabled = v.(string)
-					case "flapdetectionoptions":
-						host.flapdetectionoptions = v.(string)
-					case "processperfdata":
-						host.processperfdata = v.(string)
-					case "retainstatusinfo":
-						host.retainstatusinfo = v.(string)
-					case "retainnonstatusinfo":
-						host.retainnonstatusinfo = v.(string)
-					case "notifinterval":
-						host.notifinterval = v.(string)
-					case "firstnotifdelay":
-						host.firstnotifdelay = v.(string)
-					case "notifperiod":
-						host.notifperiod = v.(string)
-					case "notifopts":
-						host.notifopts = v.(string)
-					case "notifications_enabled":
-						host.notifications_enabled = v.(string)
-					case "stalkingoptions":
-						host.stalkingoptions = v.(string)
-					case "notes":
-						host.notes = v.(string)
-					case "notes_url":
-						host.notes_url = v.(string)
-					case "icon_image":
-						host.icon_image = v.(string)
-					case "icon_image_alt":
-						host.icon_image_alt = v.(string)
-					case "vrml_image":
-						host.vrml_image = v.(string)
-					case "statusmap_image":
-						host.statusmap_image = v.(string)
-					case "coords2d":
-						host.coords2d = v.(string)
-					case "coords3d":
-						host.coords3d = v.(string)
-					case "action_url":
-						host.action_url = v.(string)
-					case "customvars":
-						host.customvars = v.(string)
-					}
+	body, err := clientOrDefault(h.client).get(ctx, url, endpoint, folder,
+		"hosts", data)
+	if err != nil {
+		return err
+	}
+
+	// [[{"name":"web1"},{"alias":"Web server"},...],...]
+	var reply [][]map[string]string
+	if err := json.Unmarshal(body, &reply); err != nil {
+		return DecodeError{body, err}
+	}
+
+	t, _ := Table("hosts")
+
+	for _, j := range reply {
+		host := Host{}
+		for _, content := range j {
+			for k, val := range content {
+				if t.IsEncoded(k) {
+					val, _ = UrlDecode(val)
 				}
+				host.SetField(k, val)
 			}
-			h.hosts = append(h.hosts, host)
 		}
-
-		return nil
+		h.hosts = append(h.hosts, host)
 	}
+
+	return nil
 }
 
 /*
- * Send HTTP POST request
+ * Fetch the records of the client's folder that pass q's filter, then
+ * apply q's column selection and sort order. See Query for which part of
+ * the filter the server is asked to apply.
  */
-func (h Hosts) Post(url, endpoint, folder string, data []string) (e error) {
+func (h *Hosts) GetQuery(ctx context.Context, q Query) error {
+
+	t, _ := Table("hosts")
 
-	for strings.HasSuffix(url, "/") {
-		url = strings.TrimSuffix(url, "/")
+	data, f, err := showData(t, q)
+	if err != nil {
+		return err
 	}
-	for strings.HasSuffix(endpoint, "/") {
-		url = strings.TrimSuffix(endpoint, "/")
+	if len(q.Columns) > 0 {
+		if err := checkColumns(h.Options(), "hosts", q.Columns); err != nil {
+			return err
+		}
+	}
+	order := []sortKey(nil)
+	if len(q.Sort) > 0 {
+		if order, err = parseSort(h.Options(), "hosts", q.Sort); err != nil {
+			return err
+		}
 	}
 
-	fullUrl := url + "/" + endpoint
+	got := Hosts{client: h.client}
+	if err := got.GetContext(ctx, "", "rest/show/hosts", "", data); err != nil {
+		return err
+	}
 
-	// Format data
-	dataStr := "json={\"folder\":\"" + folder + "\""
-	dataInr, err := FormatData(data, "hosts")
-	if err != nil {
-		txt := fmt.Sprintf("Could not format data. Check the '-d' option.")
-		return HttpError{txt}
+	for _, k := range got.hosts {
+		if f == nil || f.Match(k) {
+			h.hosts = append(h.hosts, k)
+		}
 	}
-	if dataInr != "" {
-		dataStr += "," + dataInr
+	if len(q.Columns) > 0 {
+		h.columns = append([]string{}, q.Columns...)
 	}
-	dataStr += "}"
+	if order != nil {
+		h.order = order
+	}
+
+	return nil
+}
+
+/*
+ * Send HTTP POST request
+ */
+func (h Hosts) Post(url, endpoint, folder string, data []string) (e error) {
+	return h.PostContext(context.Background(), url, endpoint, folder, data)
+}
 
-	//fmt.Printf("URL=%s\n", fullUrl)
-	//fmt.Printf("Data=%s\n", dataStr)
+func (h Hosts) PostContext(ctx context.Context, url, endpoint,
+	folder string, data []string) (e error) {
 
-	buf := bytes.NewBuffer([]byte(dataStr))
+	_, err := clientOrDefault(h.client).post(ctx, url, endpoint, folder,
+		"hosts", data)
 
-	//fmt.Printf("json=%s\n", buf)
+	return err
+}
 
-	// accept bad certs
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-	client := &http.Client{Transport: tr}
+/*
+ * Add a record to the client's folder. The required fields are checked
+ * before anything is sent.
+ */
+func (h Hosts) Add(ctx context.Context, r Host) error {
 
-	//resp, err := client.Post(fullUrl, "application/x-www-form-urlencoded", buf)
-	req, err := http.NewRequest("POST", fullUrl, buf)
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	if len(h.username) > 0 {
-		req.SetBasicAuth(h.username, h.password)
-	}
-	resp, err := client.Do(req)
+	data, err := addData("hosts", r)
 	if err != nil {
-		txt := fmt.Sprintf("Could not send REST request ('%s').", err.Error())
-		return HttpError{txt}
+		return err
 	}
 
-	defer resp.Body.Close()
+	return h.PostContext(ctx, "", "rest/add/hosts", "", data)
+}
 
-	var body []byte
-	if b, err := ioutil.ReadAll(resp.Body); err != nil {
-		txt := fmt.Sprintf("Error reading Body ('%s').", err.Error())
-		return HttpError{txt}
-	} else {
-		body = b
+/*
+ * Modify the record identified by the key fields of key. Changes map
+ * field names to their new values.
+ */
+func (h Hosts) Modify(ctx context.Context, key Host,
+	changes map[string]string) error {
+
+	data, err := modifyData("hosts", key, changes)
+	if err != nil {
+		return err
 	}
 
-	if resp.StatusCode != 200 {
+	return h.PostContext(ctx, "", "rest/modify/hosts", "", data)
+}
 
-		response, _ := UrlDecode(string(body))
-		txt := fmt.Sprintf("Status (%d): %s", resp.StatusCode, response)
-		return HttpError{txt}
+/*
+ * Delete the record identified by the key fields of key
+ */
+func (h Hosts) Delete(ctx context.Context, key Host) error {
 
+	data, err := keyData("hosts", key)
+	if err != nil {
+		return err
 	}
 
-	// If we get here then it was a success
-	return nil
+	return h.PostContext(ctx, "", "rest/delete/hosts", "", data)
 }
@@ -0,0 +1,230 @@
+// Command gen writes the table files of package nrc from schema.json.
+//
+// It is run by 'go generate' in the package directory. With -check it
+// writes nothing and exits with status 1 if any generated file is stale.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+type table struct {
+	Name     string   `json:"name"`
+	Record   string   `json:"record"` // exported record type
+	Fields   []string `json:"fields"`
+	Required []string `json:"required"`
+	Encoded  []string `json:"encoded"`
+	Keys     []string `json:"keys"`
+}
+
+type schema struct {
+	Tables []table  `json:"tables"`
+	Order  []string `json:"order"` // tables, each after those it refers to
+}
+
+type field struct {
+	Name string // nagrestconf name, notes_url
+	Go   string // exported Go name, NotesUrl
+}
+
+// The values a table file template is executed with
+type tableData struct {
+	Table  string // hosts
+	Type   string // Hosts
+	Var    string // host
+	Record string // Host
+	Fields []field
+}
+
+const registryTmpl = `// Code generated by internal/gen from schema.json; DO NOT EDIT.
+
+package nrc
+
+var tableNames = []string{ {{- range .Tables}}{{printf "%q" .Name}}, {{end -}} }
+
+// Tables in an order that lets each be added after the tables its
+// records refer to
+var dependencyOrder = {{template "list" .Order}}
+
+var tableInfo = map[string]TableInfo{
+{{- range .Tables}}
+	{{printf "%q" .Name}}: {
+		Name:     {{printf "%q" .Name}},
+		Fields:   {{template "list" .Fields}},
+		Required: {{template "list" .Required}},
+		Encoded:  {{template "list" .Encoded}},
+		Keys:     {{template "list" .Keys}},
+	},
+{{- end}}
+}
+
+/*
+ * Return an empty record of the named table, or nil
+ */
+func newRecord(table string) FieldSetter {
+	switch table {
+{{- range .Tables}}
+	case {{printf "%q" .Name}}:
+		return &{{.Record}}{}
+{{- end}}
+	}
+	return nil
+}
+{{define "list"}}[]string{ {{- range .}}{{printf "%q" .}}, {{end -}} }{{end}}
+`
+
+var underscore = regexp.MustCompile(`(^|_)([a-z])`)
+
+/*
+ * notes_url -> NotesUrl
+ */
+func exported(name string) string {
+	return underscore.ReplaceAllStringFunc(name, func(s string) string {
+		return strings.ToUpper(strings.TrimPrefix(s, "_"))
+	})
+}
+
+func newTableData(t table) tableData {
+
+	d := tableData{}
+	d.Table = t.Name
+	d.Type = strings.ToUpper(t.Name[:1]) + t.Name[1:]
+	d.Var = strings.TrimSuffix(t.Name, "s")
+	d.Record = t.Record
+	for _, i := range t.Fields {
+		d.Fields = append(d.Fields, field{i, exported(i)})
+	}
+
+	return d
+}
+
+/*
+ * Execute tmpl with data and gofmt the result
+ */
+func render(tmpl *template.Template, data interface{}) ([]byte, error) {
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+
+	return format.Source(buf.Bytes())
+}
+
+/*
+ * Check that order names every table exactly once
+ */
+func checkOrder(s schema) error {
+
+	seen := make(map[string]bool)
+	for _, i := range s.Order {
+		if seen[i] {
+			return fmt.Errorf("order: %s is listed twice", i)
+		}
+		seen[i] = true
+	}
+	for _, t := range s.Tables {
+		if !seen[t.Name] {
+			return fmt.Errorf("order: %s is missing", t.Name)
+		}
+		delete(seen, t.Name)
+	}
+	for i := range seen {
+		return fmt.Errorf("order: %s is not a table", i)
+	}
+
+	return nil
+}
+
+/*
+ * Return the contents of every generated file keyed by file name
+ */
+func generate(genDir string, s schema) (map[string][]byte, error) {
+
+	if err := checkOrder(s); err != nil {
+		return nil, err
+	}
+
+	files := make(map[string][]byte)
+
+	tableTmpl, err := template.ParseFiles(filepath.Join(genDir, "table.tmpl"))
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range s.Tables {
+		src, err := render(tableTmpl, newTableData(t))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", t.Name, err.Error())
+		}
+		files[t.Name+".go"] = src
+	}
+
+	regTmpl := template.Must(template.New("tables").Parse(registryTmpl))
+	src, err := render(regTmpl, s)
+	if err != nil {
+		return nil, fmt.Errorf("tables: %s", err.Error())
+	}
+	files["tables.go"] = src
+
+	return files, nil
+}
+
+func main() {
+
+	check := flag.Bool("check", false, "fail if generated files are stale")
+	dir := flag.String("dir", ".", "package directory to write to")
+	genDir := flag.String("gen", "internal/gen",
+		"directory holding schema.json and table.tmpl")
+	flag.Parse()
+
+	js, err := ioutil.ReadFile(filepath.Join(*genDir, "schema.json"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gen: %s\n", err.Error())
+		os.Exit(2)
+	}
+	var s schema
+	if err := json.Unmarshal(js, &s); err != nil {
+		fmt.Fprintf(os.Stderr, "gen: schema.json: %s\n", err.Error())
+		os.Exit(2)
+	}
+
+	files, err := generate(*genDir, s)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "gen: %s\n", err.Error())
+		os.Exit(2)
+	}
+
+	stale := 0
+	for name, src := range files {
+		path := filepath.Join(*dir, name)
+		old, _ := ioutil.ReadFile(path)
+		if bytes.Equal(old, src) {
+			continue
+		}
+		if *check {
+			fmt.Fprintf(os.Stderr, "gen: %s is stale\n", path)
+			stale++
+			continue
+		}
+		if err := ioutil.WriteFile(path, src, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "gen: %s\n", err.Error())
+			os.Exit(2)
+		}
+	}
+
+	if stale > 0 {
+		fmt.Fprintf(os.Stderr, "gen: run 'go generate' to update %d file(s)\n",
+			stale)
+		os.Exit(1)
+	}
+}
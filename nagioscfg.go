@@ -0,0 +1,412 @@
+package nrc
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+ * Native Nagios object configuration and the nagrestconf tables.
+ *
+ * Each Nagios object type maps onto a table, and host and service
+ * templates (objects with 'register 0') onto the template tables. Other
+ * object types have no template table so their templates are merged
+ * into the objects that use them.
+ *
+ * Nagios lists are comma separated and nagrestconf lists are space
+ * separated. Custom variables, _NAME value, are kept in customvars as
+ * "_NAME value" entries separated by ';'. Timeperiod weekday ranges go
+ * in definition and all other ranges in exception, also ';' separated.
+ */
+
+// NagiosConfig holds the records read from Nagios object configuration,
+// one slice per table, and the directives that could not be mapped onto
+// any field.
+type NagiosConfig struct {
+	Hosts            []Host
+	Services         []Service
+	Hosttemplates    []HostTemplate
+	Servicetemplates []ServiceTemplate
+	Hostgroups       []HostGroup
+	Servicegroups    []ServiceGroup
+	Contacts         []Contact
+	Contactgroups    []ContactGroup
+	Timeperiods      []Timeperiod
+	Commands         []Command
+	Servicedeps      []ServiceDependency
+	Hostdeps         []HostDependency
+	Serviceesc       []ServiceEscalation
+	Hostesc          []HostEscalation
+	Serviceextinfo   []ServiceExtInfo
+	Hostextinfo      []HostExtInfo
+
+	Unmapped []UnmappedDirective
+}
+
+// UnmappedDirective is a directive, or a whole object, that has no
+// place in the nagrestconf tables. Settings found outside any define
+// block have no Object.
+type UnmappedDirective struct {
+	File      string
+	Line      int
+	Object    string // object type, e.g. host
+	Name      string // object or template name
+	Directive string // empty when the whole object was skipped
+	Value     string
+	Reason    string
+}
+
+func (u UnmappedDirective) String() string {
+
+	name := u.Object
+	if u.Name != "" {
+		name += " '" + u.Name + "'"
+	}
+	if name == "" {
+		return fmt.Sprintf("%s:%d: %s: %s", u.File, u.Line, u.Directive,
+			u.Reason)
+	}
+	if u.Directive == "" {
+		return fmt.Sprintf("%s:%d: %s: %s", u.File, u.Line, name, u.Reason)
+	}
+
+	return fmt.Sprintf("%s:%d: %s: %s: %s", u.File, u.Line, name, u.Directive,
+		u.Reason)
+}
+
+// A directive and the fields it maps onto. The first field the table
+// has is used, so one entry can serve an object table and its template
+// table.
+type cfgField struct {
+	directive string
+	fields    []string
+}
+
+// How one Nagios object type maps onto the tables
+type cfgKind struct {
+	table     string // object table
+	templates string // template table, empty to merge templates
+	name      string // directive naming an object, the template's is 'name'
+	hosts     string // field filled by expanding host_name/hostgroup_name
+	fields    []cfgField
+}
+
+func cf(directive string, fields ...string) cfgField {
+	return cfgField{directive, fields}
+}
+
+// Directives shared by hosts and services
+var cfgCheckFields = []cfgField{
+	cf("display_name", "displayname"),
+	cf("initial_state", "initialstate"),
+	cf("max_check_attempts", "maxcheckattempts"),
+	cf("check_interval", "checkinterval"),
+	cf("normal_check_interval", "checkinterval"),
+	cf("retry_interval", "retryinterval"),
+	cf("retry_check_interval", "retryinterval"),
+	cf("active_checks_enabled", "activechecks"),
+	cf("passive_checks_enabled", "passivechecks"),
+	cf("check_period", "checkperiod"),
+	cf("check_freshness", "checkfreshness"),
+	cf("freshness_threshold", "freshnessthresh"),
+	cf("event_handler", "eventhandler"),
+	cf("event_handler_enabled", "eventhandlerenabled"),
+	cf("low_flap_threshold", "lowflapthresh"),
+	cf("high_flap_threshold", "highflapthresh"),
+	cf("flap_detection_enabled", "flapdetectionenabled"),
+	cf("flap_detection_options", "flapdetectionoptions"),
+	cf("process_perf_data", "processperfdata"),
+	cf("retain_status_information", "retainstatusinfo"),
+	cf("retain_nonstatus_information", "retainnonstatusinfo"),
+	cf("contact_groups", "contactgroups"),
+	cf("notification_interval", "notifinterval"),
+	cf("first_notification_delay", "firstnotifdelay"),
+	cf("notification_period", "notifperiod"),
+	cf("notification_options", "notifopts"),
+	cf("notifications_enabled", "notifications_enabled"),
+	cf("stalking_options", "stalkingoptions"),
+	cf("notes", "notes"),
+	cf("notes_url", "notes_url"),
+	cf("action_url", "action_url"),
+	cf("icon_image", "icon_image"),
+	cf("icon_image_alt", "icon_image_alt"),
+}
+
+var cfgKinds = map[string]cfgKind{
+	"host": {"hosts", "hosttemplates", "host_name", "", append([]cfgField{
+		cf("host_name", "name"),
+		cf("alias", "alias"),
+		cf("address", "ipaddress"),
+		cf("parents", "parents"),
+		cf("hostgroups", "hostgroup"),
+		cf("check_command", "command", "checkcommand"),
+		cf("contacts", "contact", "contacts"),
+		cf("obsess_over_host", "obsessoverhost"),
+		cf("obsess", "obsessoverhost"),
+		cf("vrml_image", "vrml_image"),
+		cf("statusmap_image", "statusmap_image"),
+		cf("2d_coords", "coords2d"),
+		cf("3d_coords", "coords3d"),
+	}, cfgCheckFields...)},
+	"service": {"services", "servicetemplates", "service_description", "name",
+		append([]cfgField{
+			cf("service_description", "svcdesc"),
+			cf("check_command", "command"),
+			cf("servicegroups", "svcgroup"),
+			cf("contacts", "contacts"),
+			cf("is_volatile", "isvolatile"),
+			cf("obsess_over_service", "obsessoverservice"),
+			cf("obsess", "obsessoverservice"),
+		}, cfgCheckFields...)},
+	"hostgroup": {"hostgroups", "", "hostgroup_name", "", []cfgField{
+		cf("hostgroup_name", "name"),
+		cf("alias", "alias"),
+		cf("members", "members"),
+		cf("hostgroup_members", "hostgroupmembers"),
+		cf("notes", "notes"),
+		cf("notes_url", "notes_url"),
+		cf("action_url", "action_url"),
+	}},
+	"servicegroup": {"servicegroups", "", "servicegroup_name", "", []cfgField{
+		cf("servicegroup_name", "name"),
+		cf("alias", "alias"),
+		cf("members", "members"),
+		cf("servicegroup_members", "servicegroupmembers"),
+		cf("notes", "notes"),
+		cf("notes_url", "notes_url"),
+		cf("action_url", "action_url"),
+	}},
+	"contact": {"contacts", "", "contact_name", "", []cfgField{
+		cf("contact_name", "name"),
+		cf("alias", "alias"),
+		cf("email", "emailaddr"),
+		cf("pager", "pager"),
+		cf("address1", "address1"),
+		cf("address2", "address2"),
+		cf("address3", "address3"),
+		cf("address4", "address4"),
+		cf("address5", "address5"),
+		cf("address6", "address6"),
+		cf("contactgroups", "contactgroups"),
+		cf("service_notification_period", "svcnotifperiod"),
+		cf("service_notification_options", "svcnotifopts"),
+		cf("service_notification_commands", "svcnotifcmds"),
+		cf("service_notifications_enabled", "svcnotifenabled"),
+		cf("host_notification_period", "hstnotifperiod"),
+		cf("host_notification_options", "hstnotifopts"),
+		cf("host_notification_commands", "hstnotifcmds"),
+		cf("host_notifications_enabled", "hstnotifenabled"),
+		cf("can_submit_commands", "cansubmitcmds"),
+		cf("retain_status_information", "retainstatusinfo"),
+		cf("retain_nonstatus_information", "retainnonstatusinfo"),
+	}},
+	"contactgroup": {"contactgroups", "", "contactgroup_name", "", []cfgField{
+		cf("contactgroup_name", "name"),
+		cf("alias", "alias"),
+		cf("members", "members"),
+	}},
+	"timeperiod": {"timeperiods", "", "timeperiod_name", "", []cfgField{
+		cf("timeperiod_name", "name"),
+		cf("alias", "alias"),
+		cf("exclude", "exclude"),
+	}},
+	"command": {"commands", "", "command_name", "", []cfgField{
+		cf("command_name", "name"),
+		cf("command_line", "command"),
+	}},
+	"servicedependency": {"servicedeps", "", "", "", []cfgField{
+		cf("dependent_host_name", "dephostname"),
+		cf("dependent_hostgroup_name", "dephostgroupname"),
+		cf("dependent_service_description", "depsvcdesc"),
+		cf("host_name", "hostname"),
+		cf("hostgroup_name", "hostgroupname"),
+		cf("service_description", "svcdesc"),
+		cf("inherits_parent", "inheritsparent"),
+		cf("execution_failure_criteria", "execfailcriteria"),
+		cf("notification_failure_criteria", "notiffailcriteria"),
+		cf("dependency_period", "period"),
+	}},
+	"hostdependency": {"hostdeps", "", "", "", []cfgField{
+		cf("dependent_host_name", "dephostname"),
+		cf("dependent_hostgroup_name", "dephostgroupname"),
+		cf("host_name", "hostname"),
+		cf("hostgroup_name", "hostgroupname"),
+		cf("inherits_parent", "inheritsparent"),
+		cf("execution_failure_criteria", "execfailcriteria"),
+		cf("notification_failure_criteria", "notiffailcriteria"),
+		cf("dependency_period", "period"),
+	}},
+	"serviceescalation": {"serviceesc", "", "", "", []cfgField{
+		cf("host_name", "hostname"),
+		cf("hostgroup_name", "hostgroupname"),
+		cf("service_description", "svcdesc"),
+		cf("contacts", "contacts"),
+		cf("contact_groups", "contactgroups"),
+		cf("first_notification", "firstnotif"),
+		cf("last_notification", "lastnotif"),
+		cf("notification_interval", "notifinterval"),
+		cf("escalation_period", "period"),
+		cf("escalation_options", "escopts"),
+	}},
+	"hostescalation": {"hostesc", "", "", "", []cfgField{
+		cf("host_name", "hostname"),
+		cf("hostgroup_name", "hostgroupname"),
+		cf("contacts", "contacts"),
+		cf("contact_groups", "contactgroups"),
+		cf("first_notification", "firstnotif"),
+		cf("last_notification", "lastnotif"),
+		cf("notification_interval", "notifinterval"),
+		cf("escalation_period", "period"),
+		cf("escalation_options", "escopts"),
+	}},
+	"serviceextinfo": {"serviceextinfo", "", "", "hostname", []cfgField{
+		cf("service_description", "svcdesc"),
+		cf("notes", "notes"),
+		cf("notes_url", "notes_url"),
+		cf("action_url", "action_url"),
+		cf("icon_image", "icon_image"),
+		cf("icon_image_alt", "icon_image_alt"),
+	}},
+	"hostextinfo": {"hostextinfo", "", "", "hostname", []cfgField{
+		cf("notes", "notes"),
+		cf("notes_url", "notes_url"),
+		cf("action_url", "action_url"),
+		cf("icon_image", "icon_image"),
+		cf("icon_image_alt", "icon_image_alt"),
+		cf("vrml_image", "vrml_image"),
+		cf("statusmap_image", "statusmap_image"),
+		cf("2d_coords", "coords2d"),
+		cf("3d_coords", "coords3d"),
+	}},
+}
+
+// Fields holding lists, space separated in nagrestconf
+var cfgListFields = map[string]bool{
+	"parents": true, "hostgroup": true, "contact": true, "contacts": true,
+	"contactgroups": true, "svcgroup": true, "members": true,
+	"hostgroupmembers": true, "servicegroupmembers": true, "template": true,
+	"use": true, "notifopts": true, "flapdetectionoptions": true,
+	"stalkingoptions": true, "escopts": true, "execfailcriteria": true,
+	"notiffailcriteria": true, "svcnotifopts": true, "hstnotifopts": true,
+	"svcnotifcmds": true, "hstnotifcmds": true, "exclude": true,
+	"dephostname": true, "dephostgroupname": true, "hostname": true,
+	"hostgroupname": true,
+}
+
+var cfgWeekdays = map[string]bool{
+	"sunday": true, "monday": true, "tuesday": true, "wednesday": true,
+	"thursday": true, "friday": true, "saturday": true,
+}
+
+/*
+ * Return the field of table that directive maps onto
+ */
+func (k cfgKind) field(t TableInfo, directive string) (string, bool) {
+
+	for _, i := range k.fields {
+		if i.directive != directive {
+			continue
+		}
+		for _, j := range i.fields {
+			if t.HasField(j) {
+				return j, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+/*
+ * Split a Nagios list, "a, b,c", into its items
+ */
+func splitCfgList(s string) []string {
+
+	items := []string{}
+	for _, i := range strings.Split(s, ",") {
+		if i = strings.TrimSpace(i); i != "" {
+			items = append(items, i)
+		}
+	}
+
+	return items
+}
+
+/*
+ * Add a record to the slice for table
+ */
+func (c *NagiosConfig) add(table string, vals map[string]string) {
+
+	set := func(r interface{ SetField(name, value string) bool }) {
+		for k, v := range vals {
+			r.SetField(k, v)
+		}
+	}
+
+	switch table {
+	case "hosts":
+		r := Host{}
+		set(&r)
+		c.Hosts = append(c.Hosts, r)
+	case "services":
+		r := Service{}
+		set(&r)
+		c.Services = append(c.Services, r)
+	case "hosttemplates":
+		r := HostTemplate{}
+		set(&r)
+		c.Hosttemplates = append(c.Hosttemplates, r)
+	case "servicetemplates":
+		r := ServiceTemplate{}
+		set(&r)
+		c.Servicetemplates = append(c.Servicetemplates, r)
+	case "hostgroups":
+		r := HostGroup{}
+		set(&r)
+		c.Hostgroups = append(c.Hostgroups, r)
+	case "servicegroups":
+		r := ServiceGroup{}
+		set(&r)
+		c.Servicegroups = append(c.Servicegroups, r)
+	case "contacts":
+		r := Contact{}
+		set(&r)
+		c.Contacts = append(c.Contacts, r)
+	case "contactgroups":
+		r := ContactGroup{}
+		set(&r)
+		c.Contactgroups = append(c.Contactgroups, r)
+	case "timeperiods":
+		r := Timeperiod{}
+		set(&r)
+		c.Timeperiods = append(c.Timeperiods, r)
+	case "commands":
+		r := Command{}
+		set(&r)
+		c.Commands = append(c.Commands, r)
+	case "servicedeps":
+		r := ServiceDependency{}
+		set(&r)
+		c.Servicedeps = append(c.Servicedeps, r)
+	case "hostdeps":
+		r := HostDependency{}
+		set(&r)
+		c.Hostdeps = append(c.Hostdeps, r)
+	case "serviceesc":
+		r := ServiceEscalation{}
+		set(&r)
+		c.Serviceesc = append(c.Serviceesc, r)
+	case "hostesc":
+		r := HostEscalation{}
+		set(&r)
+		c.Hostesc = append(c.Hostesc, r)
+	case "serviceextinfo":
+		r := ServiceExtInfo{}
+		set(&r)
+		c.Serviceextinfo = append(c.Serviceextinfo, r)
+	case "hostextinfo":
+		r := HostExtInfo{}
+		set(&r)
+		c.Hostextinfo = append(c.Hostextinfo, r)
+	}
+}
@@ -0,0 +1,402 @@
+// Package nrctest provides an in-process fake nagrestconf REST server for
+// tests. It implements the show, add, modify and delete requests for every
+// table, including show's column filter, along with check, apply, apply
+// last good and restart, keeping each folder's tables in memory.
+package nrctest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	nrc "github.com/mclarkson/nagrestconf-golib"
+)
+
+type row map[string]string
+
+// Server is a running fake nagrestconf server. Close it when done.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	folders  map[string]map[string][]row
+	calls    map[string]int
+	requests []string
+}
+
+/*
+ * Start a fake server listening on plain HTTP
+ */
+func NewServer() *Server {
+	s := newServer()
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+/*
+ * Start a fake server listening on HTTPS with a self-signed certificate.
+ * The client from NrcClient trusts it.
+ */
+func NewTLSServer() *Server {
+	s := newServer()
+	s.Server = httptest.NewTLSServer(s)
+	return s
+}
+
+func newServer() *Server {
+	s := &Server{}
+	s.folders = make(map[string]map[string][]row)
+	s.calls = make(map[string]int)
+	return s
+}
+
+/*
+ * Return an nrc.Client that talks to this server using folder
+ */
+func (s *Server) NrcClient(folder string) *nrc.Client {
+	c := nrc.NewClient(s.URL, "", "", folder)
+	c.HTTPClient = s.Server.Client()
+	return c
+}
+
+/*
+ * Return the number of requests received for endpoint, for example
+ * "rest/restart/nagios"
+ */
+func (s *Server) Calls(endpoint string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[strings.Trim(endpoint, "/")]
+}
+
+/*
+ * Return every endpoint requested so far, in the order received
+ */
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.requests...)
+}
+
+/*
+ * Add records to a table without going through the REST interface.
+ * Unknown tables are ignored.
+ */
+func (s *Server) Load(folder, table string, records ...map[string]string) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := nrc.Table(table); !ok {
+		return
+	}
+
+	t := s.rows(folder, table)
+	for _, r := range records {
+		n := row{}
+		for k, v := range r {
+			n[k] = v
+		}
+		t = append(t, n)
+	}
+	s.store(folder, table, t)
+}
+
+/*
+ * Return a copy of the records held for a table
+ */
+func (s *Server) Records(folder, table string) []map[string]string {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := []map[string]string{}
+	for _, r := range s.rows(folder, table) {
+		n := map[string]string{}
+		for k, v := range r {
+			n[k] = v
+		}
+		out = append(out, n)
+	}
+
+	return out
+}
+
+func (s *Server) rows(folder, table string) []row {
+	return s.folder(folder)[table]
+}
+
+func (s *Server) store(folder, table string, t []row) {
+	s.folder(folder)[table] = t
+}
+
+func (s *Server) folder(name string) map[string][]row {
+
+	f, ok := s.folders[name]
+	if !ok {
+		f = make(map[string][]row)
+		s.folders[name] = f
+	}
+
+	return f
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	endpoint := strings.Trim(r.URL.Path, "/")
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls[endpoint]++
+	s.requests = append(s.requests, endpoint)
+
+	req, err := decode(r)
+	if err != nil {
+		reply(w, 400, []string{"ERROR: " + err.Error()})
+		return
+	}
+	folder := req["folder"]
+	if folder == "" {
+		reply(w, 400, []string{"ERROR: folder must be specified."})
+		return
+	}
+
+	split := strings.Split(endpoint, "/")
+	if len(split) != 3 || split[0] != "rest" {
+		reply(w, 404, []string{"ERROR: Unknown endpoint " + endpoint + "."})
+		return
+	}
+	verb, table := split[1], split[2]
+
+	switch endpoint {
+	case "rest/check/nagiosconfig":
+		reply(w, 200, []string{"Total Warnings: 0", "Total Errors:   0",
+			"Things look okay - No serious problems were detected " +
+				"during the pre-flight check"})
+		return
+	case "rest/apply/nagiosconfig":
+		reply(w, 200, []string{"Configuration applied for " + folder + "."})
+		return
+	case "rest/apply/nagioslastgoodconfig", "rest/restart/nagios":
+		reply(w, 200, []string{"SUCCESS"})
+		return
+	}
+
+	t, ok := nrc.Table(table)
+	if !ok {
+		reply(w, 404, []string{"ERROR: Unknown table " + table + "."})
+		return
+	}
+
+	wantMethod := "POST"
+	if verb == "show" {
+		wantMethod = "GET"
+	}
+	if r.Method != wantMethod {
+		reply(w, 405, []string{"ERROR: Use " + wantMethod + "."})
+		return
+	}
+
+	delete(req, "folder")
+
+	// show sends encoded fields url-encoded, so requests that store
+	// or match them arrive that way too
+	if verb != "show" {
+		for _, i := range t.Encoded {
+			if v, ok := req[i]; ok {
+				if req[i], err = url.QueryUnescape(v); err != nil {
+					reply(w, 400, []string{"ERROR: Could not decode '" +
+						i + "'."})
+					return
+				}
+			}
+		}
+	}
+
+	switch verb {
+	case "show":
+		s.show(w, folder, t, req)
+	case "add":
+		s.add(w, folder, t, req)
+	case "modify":
+		s.modify(w, folder, t, req)
+	case "delete":
+		s.delete(w, folder, t, req)
+	default:
+		reply(w, 404, []string{"ERROR: Unknown endpoint " + endpoint + "."})
+	}
+}
+
+func (s *Server) show(w http.ResponseWriter, folder string, t nrc.TableInfo,
+	req row) {
+
+	// Like nagrestconf, filter is a regex applied to column, counting
+	// table columns from 1
+	var regex *regexp.Regexp
+	column := ""
+	if req["filter"] != "" {
+		n, err := strconv.Atoi(req["column"])
+		if err != nil || n < 1 || n > len(t.Fields) {
+			reply(w, 400, []string{"ERROR: Invalid column '" +
+				req["column"] + "'."})
+			return
+		}
+		column = t.Fields[n-1]
+		if regex, err = regexp.Compile(req["filter"]); err != nil {
+			reply(w, 400, []string{"ERROR: Invalid filter."})
+			return
+		}
+	}
+
+	out := [][]map[string]string{}
+	for _, r := range s.rows(folder, t.Name) {
+		if regex != nil && !regex.MatchString(r[column]) {
+			continue
+		}
+		fields := []map[string]string{}
+		for _, i := range t.Fields {
+			val := r[i]
+			if t.IsEncoded(i) {
+				val = url.QueryEscape(val)
+			}
+			fields = append(fields, map[string]string{i: val})
+		}
+		out = append(out, fields)
+	}
+
+	reply(w, 200, out)
+}
+
+func (s *Server) add(w http.ResponseWriter, folder string, t nrc.TableInfo,
+	req row) {
+
+	if msg := checkFields(t, req); msg != "" {
+		reply(w, 400, []string{"ERROR: " + msg})
+		return
+	}
+	for _, i := range t.Required {
+		if req[i] == "" {
+			reply(w, 400, []string{"ERROR: Required field '" + i +
+				"' is missing."})
+			return
+		}
+	}
+
+	rows := s.rows(folder, t.Name)
+	if find(t, rows, req) >= 0 {
+		reply(w, 400, []string{"ERROR: Item already exists."})
+		return
+	}
+
+	s.store(folder, t.Name, append(rows, req))
+
+	reply(w, 200, []string{"SUCCESS"})
+}
+
+func (s *Server) modify(w http.ResponseWriter, folder string,
+	t nrc.TableInfo, req row) {
+
+	if msg := checkFields(t, req); msg != "" {
+		reply(w, 400, []string{"ERROR: " + msg})
+		return
+	}
+
+	rows := s.rows(folder, t.Name)
+	i := find(t, rows, req)
+	if i < 0 {
+		reply(w, 400, []string{"ERROR: Item not found."})
+		return
+	}
+
+	n := row{}
+	for k, v := range rows[i] {
+		n[k] = v
+	}
+	for k, v := range req {
+		if v == "-" {
+			v = "" // nagrestconf clears a field set to a dash
+		}
+		n[k] = v
+	}
+	rows[i] = n
+
+	reply(w, 200, []string{"SUCCESS"})
+}
+
+func (s *Server) delete(w http.ResponseWriter, folder string,
+	t nrc.TableInfo, req row) {
+
+	rows := s.rows(folder, t.Name)
+	i := find(t, rows, req)
+	if i < 0 {
+		reply(w, 400, []string{"ERROR: Item not found."})
+		return
+	}
+
+	s.store(folder, t.Name, append(rows[:i:i], rows[i+1:]...))
+
+	reply(w, 200, []string{"SUCCESS"})
+}
+
+/*
+ * Return an error message if req names a field the table lacks
+ */
+func checkFields(t nrc.TableInfo, req row) string {
+	for k := range req {
+		if !t.HasField(k) {
+			return fmt.Sprintf("Unknown field '%s'.", k)
+		}
+	}
+	return ""
+}
+
+/*
+ * Return the index of the row whose key fields match req, or -1
+ */
+func find(t nrc.TableInfo, rows []row, req row) int {
+
+	for n, r := range rows {
+		match := true
+		for _, i := range t.Keys {
+			if r[i] != req[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return n
+		}
+	}
+
+	return -1
+}
+
+/*
+ * Extract the json document from a GET query or POST form
+ */
+func decode(r *http.Request) (row, error) {
+
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+
+	req := row{}
+	if err := json.Unmarshal([]byte(r.Form.Get("json")), &req); err != nil {
+		return nil, fmt.Errorf("could not decode json (%s)", err.Error())
+	}
+
+	return req, nil
+}
+
+func reply(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,694 @@
+package nrc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CfgError reports a problem reading Nagios configuration
+type CfgError struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e CfgError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.File, e.Msg)
+	}
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+// One directive and where it was set
+type cfgDirective struct {
+	value string
+	file  string
+	line  int
+}
+
+// Directives in the order they were first set
+type cfgDirs struct {
+	order []string
+	m     map[string]cfgDirective
+}
+
+func newCfgDirs() *cfgDirs {
+	return &cfgDirs{m: make(map[string]cfgDirective)}
+}
+
+func (d *cfgDirs) set(name string, v cfgDirective) {
+	if _, ok := d.m[name]; !ok {
+		d.order = append(d.order, name)
+	}
+	d.m[name] = v
+}
+
+func (d *cfgDirs) get(name string) string {
+	return d.m[name].value
+}
+
+// One define block
+type cfgObject struct {
+	kind string
+	file string
+	line int
+	dirs *cfgDirs
+}
+
+func (o *cfgObject) isTemplate() bool {
+	return o.dirs.get("register") == "0"
+}
+
+// NagiosParser reads Nagios object configuration. Give it every file,
+// or the main nagios.cfg whose cfg_file and cfg_dir lines name them,
+// then call Config. Other settings of the main file are reported in the
+// config's Unmapped list.
+type NagiosParser struct {
+	objects  []*cfgObject
+	read     map[string]bool
+	settings []UnmappedDirective
+}
+
+func NewNagiosParser() *NagiosParser {
+	p := &NagiosParser{}
+	p.read = make(map[string]bool)
+	return p
+}
+
+/*
+ * Read all of the named files into a NagiosConfig
+ */
+func ParseNagiosConfig(paths ...string) (*NagiosConfig, error) {
+
+	p := NewNagiosParser()
+	for _, i := range paths {
+		if err := p.ParseFile(i); err != nil {
+			return nil, err
+		}
+	}
+
+	return p.Config()
+}
+
+/*
+ * Read a configuration file, or every .cfg file below a directory.
+ * Files already read are skipped.
+ */
+func (p *NagiosParser) ParseFile(path string) error {
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return CfgError{path, 0, err.Error()}
+	}
+	if fi.IsDir() {
+		return p.parseDir(path)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return CfgError{path, 0, err.Error()}
+	}
+	if p.read[abs] {
+		return nil
+	}
+	p.read[abs] = true
+
+	f, err := os.Open(path)
+	if err != nil {
+		return CfgError{path, 0, err.Error()}
+	}
+	defer f.Close()
+
+	return p.Parse(f, path)
+}
+
+/*
+ * Read every .cfg file below dir, in name order, as cfg_dir does
+ */
+func (p *NagiosParser) parseDir(dir string) error {
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return CfgError{dir, 0, err.Error()}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	for _, i := range entries {
+		path := filepath.Join(dir, i.Name())
+		if i.IsDir() {
+			if err := p.parseDir(path); err != nil {
+				return err
+			}
+			continue
+		}
+		if strings.HasSuffix(i.Name(), ".cfg") {
+			if err := p.ParseFile(path); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+/*
+ * Read configuration from r. Name is used in errors and relative
+ * cfg_file, cfg_dir, include_file and include_dir paths are taken from
+ * its directory.
+ */
+func (p *NagiosParser) Parse(r io.Reader, name string) error {
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var obj *cfgObject
+	lineNo := 0
+	text := ""
+	start := 0
+
+	for scanner.Scan() {
+		lineNo++
+
+		// Join lines continued with a backslash
+		if text == "" {
+			start = lineNo
+		}
+		text += scanner.Text()
+		if strings.HasSuffix(text, "\\") {
+			text = strings.TrimSuffix(text, "\\")
+			continue
+		}
+		line := cfgStripComment(text)
+		text = ""
+
+		if line == "" {
+			continue
+		}
+
+		if obj == nil {
+			if strings.HasPrefix(line, "define") {
+				kind := strings.TrimSpace(strings.TrimPrefix(line, "define"))
+				if !strings.HasSuffix(kind, "{") {
+					return CfgError{name, start, "expected '{' after define"}
+				}
+				kind = strings.TrimSpace(strings.TrimSuffix(kind, "{"))
+				if kind == "" {
+					return CfgError{name, start, "missing object type"}
+				}
+				obj = &cfgObject{kind, name, start, newCfgDirs()}
+				continue
+			}
+
+			// Main configuration file settings
+			eq := strings.Index(line, "=")
+			if eq < 0 {
+				return CfgError{name, start,
+					fmt.Sprintf("unexpected '%s' outside a define block", line)}
+			}
+			key := strings.TrimSpace(line[:eq])
+			val := strings.TrimSpace(line[eq+1:])
+			switch key {
+			case "cfg_file", "cfg_dir", "include_file", "include_dir":
+			default:
+				p.settings = append(p.settings, UnmappedDirective{
+					File: name, Line: start, Directive: key, Value: val,
+					Reason: "not an object file setting, ignored"})
+				continue
+			}
+			if !filepath.IsAbs(val) {
+				val = filepath.Join(filepath.Dir(name), val)
+			}
+			if err := p.ParseFile(val); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if line == "}" {
+			p.objects = append(p.objects, obj)
+			obj = nil
+			continue
+		}
+		closing := strings.HasSuffix(line, "}")
+		if closing {
+			line = strings.TrimSpace(strings.TrimSuffix(line, "}"))
+		}
+
+		key, val := cfgSplitDirective(obj.kind, line)
+		obj.dirs.set(key, cfgDirective{val, name, start})
+
+		if closing {
+			p.objects = append(p.objects, obj)
+			obj = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return CfgError{name, lineNo, err.Error()}
+	}
+	if text != "" {
+		return CfgError{name, start, "file ends with a continued line"}
+	}
+	if obj != nil {
+		return CfgError{name, obj.line,
+			fmt.Sprintf("define %s is missing its '}'", obj.kind)}
+	}
+
+	return nil
+}
+
+/*
+ * Remove comments and surrounding space. Lines starting with '#' or ';'
+ * are comments, and a ';' not escaped as '\;' starts a comment.
+ */
+func cfgStripComment(line string) string {
+
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+		return ""
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' && i+1 < len(line) && line[i+1] == ';' {
+			b.WriteByte(';')
+			i++
+			continue
+		}
+		if line[i] == ';' {
+			break
+		}
+		b.WriteByte(line[i])
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+/*
+ * Split a line into directive and value. Timeperiod ranges, such as
+ * "december 25 00:00-24:00", are keyed by everything before the first
+ * time.
+ */
+func cfgSplitDirective(kind, line string) (string, string) {
+
+	key, val := line, ""
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		key, val = line[:i], strings.TrimSpace(line[i+1:])
+	}
+
+	if kind != "timeperiod" {
+		return key, val
+	}
+	if _, ok := cfgKinds["timeperiod"].field(tableInfo["timeperiods"],
+		key); ok || key == "name" || key == "use" || key == "register" {
+		return key, val
+	}
+
+	words := strings.Fields(line)
+	for i, j := range words {
+		if strings.Contains(j, ":") && i > 0 {
+			return strings.Join(words[:i], " "), strings.Join(words[i:], " ")
+		}
+	}
+
+	return key, val
+}
+
+// Resolves templates and maps objects onto records
+type cfgBuilder struct {
+	templates map[string]*cfgObject // kind/name
+	resolved  map[*cfgObject]*cfgDirs
+	busy      map[*cfgObject]bool
+	allHosts  []string
+	groups    map[string][]string
+	reported  map[string]bool
+	config    *NagiosConfig
+}
+
+/*
+ * Resolve templates and map every object read onto the tables
+ */
+func (p *NagiosParser) Config() (*NagiosConfig, error) {
+
+	b := &cfgBuilder{}
+	b.templates = make(map[string]*cfgObject)
+	b.resolved = make(map[*cfgObject]*cfgDirs)
+	b.busy = make(map[*cfgObject]bool)
+	b.groups = make(map[string][]string)
+	b.reported = make(map[string]bool)
+	b.config = &NagiosConfig{}
+	b.config.Unmapped = append(b.config.Unmapped, p.settings...)
+
+	for _, o := range p.objects {
+		if name := o.dirs.get("name"); name != "" {
+			b.templates[o.kind+"/"+name] = o
+		}
+	}
+
+	if err := b.hostgroups(p.objects); err != nil {
+		return nil, err
+	}
+
+	for _, o := range p.objects {
+		if err := b.object(o); err != nil {
+			return nil, err
+		}
+	}
+
+	return b.config, nil
+}
+
+/*
+ * Return the directives of o merged with those of its templates. Earlier
+ * templates in 'use' win over later ones, a value starting '+' is added
+ * to the inherited one and 'null' removes a directive.
+ */
+func (b *cfgBuilder) resolve(o *cfgObject) (*cfgDirs, error) {
+
+	if d, ok := b.resolved[o]; ok {
+		return d, nil
+	}
+	if b.busy[o] {
+		return nil, CfgError{o.file, o.line,
+			fmt.Sprintf("%s template loop through '%s'", o.kind,
+				o.dirs.get("name"))}
+	}
+	b.busy[o] = true
+	defer delete(b.busy, o)
+
+	d := newCfgDirs()
+	for _, k := range o.dirs.order {
+		d.set(k, o.dirs.m[k])
+	}
+
+	for _, name := range splitCfgList(o.dirs.get("use")) {
+		t, ok := b.templates[o.kind+"/"+name]
+		if !ok {
+			return nil, CfgError{o.dirs.m["use"].file, o.dirs.m["use"].line,
+				fmt.Sprintf("%s template '%s' is not defined", o.kind, name)}
+		}
+		td, err := b.resolve(t)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range td.order {
+			if k == "name" || k == "register" || k == "use" {
+				continue
+			}
+			v, ok := d.m[k]
+			switch {
+			case !ok:
+				d.set(k, td.m[k])
+			case strings.HasPrefix(v.value, "+"):
+				v.value = "+" + strings.TrimPrefix(td.m[k].value, "+") +
+					"," + v.value[1:]
+				d.set(k, v)
+			}
+		}
+	}
+
+	for _, k := range d.order {
+		v := d.m[k]
+		v.value = strings.TrimPrefix(v.value, "+")
+		d.m[k] = v
+	}
+
+	b.resolved[o] = d
+
+	return d, nil
+}
+
+/*
+ * Work out which hosts are in each hostgroup, from hostgroup members and
+ * host hostgroups, for expanding hostgroup_name
+ */
+func (b *cfgBuilder) hostgroups(objects []*cfgObject) error {
+
+	nested := make(map[string][]string)
+
+	for _, o := range objects {
+		if o.isTemplate() || (o.kind != "host" && o.kind != "hostgroup") {
+			continue
+		}
+		d, err := b.resolve(o)
+		if err != nil {
+			return err
+		}
+		switch o.kind {
+		case "host":
+			name := d.get("host_name")
+			b.allHosts = append(b.allHosts, name)
+			for _, g := range splitCfgList(d.get("hostgroups")) {
+				b.groups[g] = append(b.groups[g], name)
+			}
+		case "hostgroup":
+			name := d.get("hostgroup_name")
+			b.groups[name] = append(b.groups[name],
+				splitCfgList(d.get("members"))...)
+			nested[name] = splitCfgList(d.get("hostgroup_members"))
+		}
+	}
+
+	var add func(g string, seen map[string]bool) []string
+	add = func(g string, seen map[string]bool) []string {
+		if seen[g] {
+			return nil
+		}
+		seen[g] = true
+		hosts := append([]string{}, b.groups[g]...)
+		for _, i := range nested[g] {
+			hosts = append(hosts, add(i, seen)...)
+		}
+		return hosts
+	}
+	for g := range nested {
+		b.groups[g] = add(g, map[string]bool{})
+	}
+
+	return nil
+}
+
+/*
+ * Return the hosts named by host_name and hostgroup_name lists, which
+ * may use '*' for all and '!name' to exclude
+ */
+func (b *cfgBuilder) expandHosts(hostList, groupList string) []string {
+
+	hosts := []string{}
+	exclude := make(map[string]bool)
+
+	for _, i := range splitCfgList(hostList) {
+		switch {
+		case i == "*":
+			hosts = append(hosts, b.allHosts...)
+		case strings.HasPrefix(i, "!"):
+			exclude[i[1:]] = true
+		default:
+			hosts = append(hosts, i)
+		}
+	}
+	for _, i := range splitCfgList(groupList) {
+		switch {
+		case i == "*":
+			hosts = append(hosts, b.allHosts...)
+		case strings.HasPrefix(i, "!"):
+			for _, j := range b.groups[i[1:]] {
+				exclude[j] = true
+			}
+		default:
+			hosts = append(hosts, b.groups[i]...)
+		}
+	}
+
+	out := []string{}
+	seen := make(map[string]bool)
+	for _, i := range hosts {
+		if !seen[i] && !exclude[i] {
+			seen[i] = true
+			out = append(out, i)
+		}
+	}
+
+	return out
+}
+
+func (b *cfgBuilder) report(o *cfgObject, name, directive string,
+	d cfgDirective, reason string) {
+
+	key := fmt.Sprintf("%s:%d:%s", d.file, d.line, directive)
+	if b.reported[key] {
+		return
+	}
+	b.reported[key] = true
+
+	b.config.Unmapped = append(b.config.Unmapped, UnmappedDirective{
+		File: d.file, Line: d.line, Object: o.kind, Name: name,
+		Directive: directive, Value: d.value, Reason: reason})
+}
+
+/*
+ * Map one object onto its table
+ */
+func (b *cfgBuilder) object(o *cfgObject) error {
+
+	k, ok := cfgKinds[o.kind]
+	if !ok {
+		b.report(o, o.dirs.get("name"), "", cfgDirective{"", o.file, o.line},
+			"no nagrestconf table for this object type")
+		return nil
+	}
+
+	template := o.isTemplate()
+	if template && k.templates == "" {
+		return nil // merged into the objects that use it
+	}
+
+	objTable := tableInfo[k.table]
+	tmplTable := TableInfo{}
+	if k.templates != "" {
+		tmplTable = tableInfo[k.templates]
+	}
+
+	full, err := b.resolve(o)
+	if err != nil {
+		return err
+	}
+
+	// Choose the directives this record holds itself
+	t := objTable
+	dirs := full
+	name := full.get(k.name)
+	if template {
+		t = tmplTable
+		dirs = o.dirs
+		name = o.dirs.get("name")
+	} else if k.templates != "" {
+		// Keep what the template table can hold in the templates, bring
+		// down the rest
+		dirs = newCfgDirs()
+		for _, i := range full.order {
+			_, own := o.dirs.m[i]
+			_, inTmpl := k.field(tmplTable, i)
+			if strings.HasPrefix(i, "_") && tmplTable.HasField("customvars") {
+				inTmpl = true
+			}
+			if own || !inTmpl {
+				dirs.set(i, full.m[i])
+			}
+		}
+	}
+
+	vals := make(map[string]string)
+	customvars := []string{}
+	definition := []string{}
+	exception := []string{}
+
+	for _, i := range dirs.order {
+		d := dirs.m[i]
+
+		switch {
+		case i == "name" || i == "register":
+			continue
+		case i == "use":
+			if k.templates == "" {
+				continue
+			}
+			field := "template"
+			if template {
+				field = "use"
+			}
+			if _, own := o.dirs.m["use"]; own {
+				vals[field] = strings.Join(splitCfgList(d.value), " ")
+			}
+			continue
+		case k.hosts != "" && (i == "host_name" || i == "hostgroup_name"):
+			continue // expanded below
+		case d.value == "null":
+			continue
+		case strings.HasPrefix(i, "_"):
+			if t.HasField("customvars") {
+				customvars = append(customvars, i+" "+d.value)
+				continue
+			}
+		case o.kind == "timeperiod" && !cfgKindHas(k, i):
+			if cfgWeekdays[strings.Fields(i)[0]] {
+				definition = append(definition, i+" "+d.value)
+			} else {
+				exception = append(exception, i+" "+d.value)
+			}
+			continue
+		}
+
+		field, ok := k.field(t, i)
+		if !ok {
+			if template {
+				if _, ok := k.field(objTable, i); ok {
+					continue // brought down into the objects
+				}
+			}
+			b.report(o, name, i, d, "no matching nagrestconf field")
+			continue
+		}
+		v := d.value
+		if cfgListFields[field] {
+			v = strings.Join(splitCfgList(v), " ")
+		}
+		vals[field] = v
+	}
+
+	if template {
+		vals["name"] = name
+	}
+	if len(customvars) > 0 {
+		vals["customvars"] = strings.Join(customvars, ";")
+	}
+	if len(definition) > 0 {
+		vals["definition"] = strings.Join(definition, ";")
+	}
+	if len(exception) > 0 {
+		vals["exception"] = strings.Join(exception, ";")
+	}
+
+	table := k.table
+	if template {
+		table = k.templates
+	}
+
+	if k.hosts == "" || template {
+		b.config.add(table, vals)
+		return nil
+	}
+
+	hosts := b.expandHosts(full.get("host_name"), full.get("hostgroup_name"))
+	if len(hosts) == 0 {
+		b.report(o, name, "", cfgDirective{"", o.file, o.line},
+			"host_name and hostgroup_name match no hosts")
+		return nil
+	}
+	for _, h := range hosts {
+		v := make(map[string]string)
+		for i, j := range vals {
+			v[i] = j
+		}
+		v[k.hosts] = h
+		b.config.add(table, v)
+	}
+
+	return nil
+}
+
+func cfgKindHas(k cfgKind, directive string) bool {
+	for _, i := range k.fields {
+		if i.directive == directive {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,430 @@
+package nrc_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	nrc "github.com/mclarkson/nagrestconf-golib"
+)
+
+/*
+ * Write files, keyed by path relative to dir
+ */
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	for name, text := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func hostDefine(name string) string {
+	return "define host {\n    host_name " + name +
+		"\n    address 10.0.0.1\n}\n"
+}
+
+func TestParseNagiosIncludes(t *testing.T) {
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"nagios.cfg": "log_file=/var/log/nagios.log\n" +
+			"cfg_file=objects/hosts.cfg\n" +
+			"cfg_dir=conf.d\n" +
+			"check_result_reaper_frequency=10\n",
+		"objects/hosts.cfg": hostDefine("web1") +
+			"include_file=more.cfg\n",
+		"objects/more.cfg": hostDefine("db1"),
+		"conf.d/a.cfg":     "include_dir=../extra\n",
+		"extra/b.cfg":      hostDefine("mail1"),
+		"extra/c.txt":      hostDefine("ignored"),
+	})
+
+	cfg, err := nrc.ParseNagiosConfig(filepath.Join(dir, "nagios.cfg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, h := range cfg.Hosts {
+		names = append(names, h.Name)
+	}
+	sort.Strings(names)
+	if want := []string{"db1", "mail1", "web1"}; !reflect.DeepEqual(names,
+		want) {
+		t.Errorf("read hosts %q, want %q", names, want)
+	}
+
+	got := []string{}
+	for _, u := range cfg.Unmapped {
+		got = append(got, u.String())
+	}
+	main := filepath.Join(dir, "nagios.cfg")
+	want := []string{
+		main + ":1: log_file: not an object file setting, ignored",
+		main + ":4: check_result_reaper_frequency: not an object file " +
+			"setting, ignored",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unmapped:\n%q\nwant:\n%q", got, want)
+	}
+	if len(cfg.Unmapped) > 0 &&
+		cfg.Unmapped[0].Value != "/var/log/nagios.log" {
+		t.Errorf("log_file value %q", cfg.Unmapped[0].Value)
+	}
+}
+
+func TestParseNagiosMissingInclude(t *testing.T) {
+
+	dir := t.TempDir()
+	writeTree(t, dir, map[string]string{
+		"nagios.cfg": "include_file=missing.cfg\n",
+	})
+
+	_, err := nrc.ParseNagiosConfig(filepath.Join(dir, "nagios.cfg"))
+	if err == nil {
+		t.Error("missing include_file was not an error")
+	}
+}
+
+/*
+ * Return the records of every non-empty table of cfg, each as a map of
+ * its non-empty fields
+ */
+func cfgTables(cfg *nrc.NagiosConfig) map[string][]map[string]string {
+
+	out := map[string][]map[string]string{}
+	v := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		if f.Kind() != reflect.Slice {
+			continue
+		}
+		for j := 0; j < f.Len(); j++ {
+			r, ok := f.Index(j).Interface().(nrc.Record)
+			if !ok {
+				break
+			}
+			t, _ := nrc.Table(r.TableName())
+			m := map[string]string{}
+			for _, k := range t.Fields {
+				if val, _ := r.Field(k); val != "" {
+					m[k] = val
+				}
+			}
+			out[t.Name] = append(out[t.Name], m)
+		}
+	}
+
+	return out
+}
+
+// Objects mapped onto records by the parser
+var parseMappingTests = []struct {
+	name string
+	cfg  string
+	want map[string][]map[string]string
+	err  string // part of the error wanted instead
+}{
+	{
+		name: "use across several templates",
+		cfg: `
+define contact {
+    name                        c-base
+    register                    0
+    email                       base@example.com
+    service_notification_period 24x7
+    host_notification_period    24x7
+}
+define contact {
+    name     c-pager
+    use      c-base
+    register 0
+    pager    555
+}
+define contact {
+    name     c-mail
+    register 0
+    email    mail@example.com
+    alias    Mail
+}
+define contact {
+    contact_name ann
+    use          c-pager, c-mail
+}
+define contact {
+    contact_name bob
+    alias        Bob
+    use          c-mail,c-pager
+}`,
+		want: map[string][]map[string]string{"contacts": {
+			{"name": "ann", "alias": "Mail", "pager": "555",
+				"emailaddr": "base@example.com", "svcnotifperiod": "24x7",
+				"hstnotifperiod": "24x7"},
+			{"name": "bob", "alias": "Bob", "pager": "555",
+				"emailaddr": "mail@example.com", "svcnotifperiod": "24x7",
+				"hstnotifperiod": "24x7"},
+		}},
+	},
+	{
+		name: "additive values and null",
+		cfg: `
+define contact {
+    name          c-base
+    register      0
+    email         base@example.com
+    contactgroups admins
+    pager         555
+}
+define contact {
+    contact_name  ann
+    use           c-base
+    contactgroups +ops,dba
+    email         null
+}`,
+		want: map[string][]map[string]string{"contacts": {
+			{"name": "ann", "pager": "555",
+				"contactgroups": "admins ops dba"},
+		}},
+	},
+	{
+		name: "host and service templates",
+		cfg: `
+define host {
+    name           web-tmpl
+    register       0
+    check_interval 5
+    display_name   Web
+    check_command  check-host-alive
+}
+define host {
+    host_name web1
+    alias     Web 1
+    address   10.0.0.1
+    use       web-tmpl
+}
+define service {
+    name               svc-tmpl
+    register           0
+    max_check_attempts 3
+    check_command      check_ping
+}
+define service {
+    host_name           web1
+    service_description PING
+    use                 svc-tmpl
+}`,
+		want: map[string][]map[string]string{
+			"hosttemplates": {
+				{"name": "web-tmpl", "checkinterval": "5",
+					"checkcommand": "check-host-alive"},
+			},
+			"hosts": {
+				{"name": "web1", "alias": "Web 1", "ipaddress": "10.0.0.1",
+					"template": "web-tmpl", "displayname": "Web"},
+			},
+			"servicetemplates": {
+				{"name": "svc-tmpl", "maxcheckattempts": "3"},
+			},
+			"services": {
+				{"name": "web1", "svcdesc": "PING", "template": "svc-tmpl",
+					"command": "check_ping"},
+			},
+		},
+	},
+	{
+		name: "hostgroup_name expansion",
+		cfg: `
+define host {
+    host_name  web1
+    hostgroups linux
+}
+define host {
+    host_name  web2
+    hostgroups linux
+}
+define host {
+    host_name  db1
+    hostgroups linux, db
+}
+define service {
+    hostgroup_name      linux, !db
+    service_description HTTP
+    check_command       check_http
+}
+define service {
+    host_name           *, !web2
+    service_description PING
+    check_command       check_ping
+}
+define hostescalation {
+    hostgroup_name db
+    host_name      web2
+    first_notification 2
+}`,
+		want: map[string][]map[string]string{
+			"hosts": {
+				{"name": "web1", "hostgroup": "linux"},
+				{"name": "web2", "hostgroup": "linux"},
+				{"name": "db1", "hostgroup": "linux db"},
+			},
+			"services": {
+				{"name": "web1", "svcdesc": "HTTP", "command": "check_http"},
+				{"name": "web2", "svcdesc": "HTTP", "command": "check_http"},
+				{"name": "web1", "svcdesc": "PING", "command": "check_ping"},
+				{"name": "db1", "svcdesc": "PING", "command": "check_ping"},
+			},
+			"hostesc": {
+				{"hostname": "web2", "hostgroupname": "db",
+					"firstnotif": "2"},
+			},
+		},
+	},
+	{
+		name: "timeperiod ranges",
+		cfg: `
+define timeperiod {
+    timeperiod_name work
+    alias           Work hours
+    monday          09:00-17:00
+    friday          09:00-12:00,13:00-17:00
+    december 25     00:00-24:00
+    2026-01-01      00:00-24:00
+    day 1 - 7       00:00-01:00
+    exclude         holidays
+}`,
+		want: map[string][]map[string]string{"timeperiods": {
+			{"name": "work", "alias": "Work hours",
+				"definition": "monday 09:00-17:00;" +
+					"friday 09:00-12:00,13:00-17:00",
+				"exception": "december 25 00:00-24:00;" +
+					"2026-01-01 00:00-24:00;day 1 - 7 00:00-01:00",
+				"exclude": "holidays"},
+		}},
+	},
+	{
+		name: "customvars",
+		cfg: `
+define host {
+    name     web-tmpl
+    register 0
+    _ZONE    dmz
+}
+define host {
+    host_name web1
+    use       web-tmpl
+    _SNMP     public
+    _RACK     r4 u12
+}
+define command {
+    command_name check_x
+    command_line $USER1$/check_x
+    _IGNORED     1
+}`,
+		want: map[string][]map[string]string{
+			"hosttemplates": {
+				{"name": "web-tmpl", "customvars": "_ZONE dmz"},
+			},
+			"hosts": {
+				{"name": "web1", "template": "web-tmpl",
+					"customvars": "_SNMP public;_RACK r4 u12"},
+			},
+			"commands": {
+				{"name": "check_x", "command": "$USER1$/check_x"},
+			},
+		},
+	},
+	{
+		name: "template loop",
+		cfg: `
+define contact {
+    name     a
+    use      b
+    register 0
+}
+define contact {
+    name     b
+    use      a
+    register 0
+}
+define contact {
+    contact_name ann
+    use          a
+}`,
+		err: "contact template loop",
+	},
+	{
+		name: "undefined template",
+		cfg: `
+define host {
+    host_name web1
+    use       missing
+}`,
+		err: "host template 'missing' is not defined",
+	},
+}
+
+func TestParseNagiosMapping(t *testing.T) {
+
+	for _, i := range parseMappingTests {
+		p := nrc.NewNagiosParser()
+		if err := p.Parse(strings.NewReader(i.cfg), "test.cfg"); err != nil {
+			t.Errorf("%s: %v", i.name, err)
+			continue
+		}
+		cfg, err := p.Config()
+		if i.err != "" {
+			if err == nil || !strings.Contains(err.Error(), i.err) {
+				t.Errorf("%s: got error %v, want %q", i.name, err, i.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", i.name, err)
+			continue
+		}
+		if got := cfgTables(cfg); !reflect.DeepEqual(got, i.want) {
+			t.Errorf("%s: got\n%q\nwant\n%q", i.name, got, i.want)
+		}
+	}
+}
+
+func TestParseNagiosRoundTrip(t *testing.T) {
+
+	// What WriteDir writes parses back to the same records
+	for n, i := range parseMappingTests {
+		if i.err != "" {
+			continue
+		}
+		dir := filepath.Join(t.TempDir(), strconv.Itoa(n))
+
+		p := nrc.NewNagiosParser()
+		if err := p.Parse(strings.NewReader(i.cfg), "test.cfg"); err != nil {
+			t.Fatalf("%s: %v", i.name, err)
+		}
+		cfg, err := p.Config()
+		if err != nil {
+			t.Fatalf("%s: %v", i.name, err)
+		}
+		if err := cfg.WriteDir(dir); err != nil {
+			t.Fatalf("%s: %v", i.name, err)
+		}
+		back, err := nrc.ParseNagiosConfig(dir)
+		if err != nil {
+			t.Errorf("%s: reading back: %v", i.name, err)
+			continue
+		}
+		if got := cfgTables(back); !reflect.DeepEqual(got, i.want) {
+			t.Errorf("%s: read back\n%q\nwant\n%q", i.name, got, i.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package nrc
+
+import (
+	"strconv"
+)
+
+// Query holds the options of a show request made with GetQuery. The zero
+// Query fetches the whole table from the client's folder.
+//
+// nagrestconf can apply one regex to one column of a table itself. When
+// Filter is a single regex test, or an AND of tests where one is a
+// regex, that regex is sent with the request so less comes back. Tests
+// of fields the server stores url-encoded, such as a service's name,
+// are never sent. The whole filter is still applied to what arrives, so
+// results are the same from servers that ignore it. Set Local to never
+// send it.
+type Query struct {
+	Filter  string   // filter expression, see CompileFilter
+	Columns []string // as for Select
+	Sort    []string // as for SortBy
+	Local   bool     // filter only on the client
+}
+
+/*
+ * Build the show request data for q against table t and compile its
+ * filter. The filter is nil if q has none.
+ */
+func showData(t TableInfo, q Query) ([]string, *Filter, error) {
+
+	if q.Filter == "" {
+		return nil, nil, nil
+	}
+
+	f, err := CompileFilter(q.Filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := f.Check(t.Fields, t.Name); err != nil {
+		return nil, nil, err
+	}
+
+	if q.Local {
+		return nil, f, nil
+	}
+
+	column, regex, ok := f.serverFilter(t)
+	if !ok {
+		return nil, f, nil
+	}
+
+	// nagrestconf numbers columns from 1 in table column order
+	data := []string{
+		"column:" + strconv.Itoa(column),
+		"filter:" + regex,
+	}
+
+	return data, f, nil
+}
+
+/*
+ * Choose a regex test the server can apply in place of the whole
+ * filter: the filter itself or one of the terms of a top level AND.
+ * Only regexes that mean the same to the server's regex engine as to
+ * Go's are used. Fields the server stores url-encoded are skipped, as
+ * the server would match the regex against the encoded value.
+ */
+func (f *Filter) serverFilter(t TableInfo) (int, string, bool) {
+
+	tests := []filterNode{f.root}
+	if and, ok := f.root.(filterAnd); ok {
+		tests = and
+	}
+
+	for _, n := range tests {
+		test, ok := n.(filterTest)
+		if !ok || (test.op != ":" && test.op != "~") ||
+			!portableRegex(test.value) || t.IsEncoded(test.field) {
+			continue
+		}
+		for i, j := range t.Fields {
+			if j == test.field {
+				return i + 1, test.value, true
+			}
+		}
+	}
+
+	return 0, "", false
+}
+
+/*
+ * Report whether regex uses only syntax shared by POSIX extended regular
+ * expressions and Go
+ */
+func portableRegex(regex string) bool {
+
+	if regex == "" {
+		return false
+	}
+
+	for i := 0; i < len(regex); i++ {
+		c := regex[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == ' ', c == '-', c == '_', c == '.', c == '^', c == '$',
+			c == '*', c == '+', c == '?', c == '|', c == '(', c == ')',
+			c == '[', c == ']':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,56 @@
+package nrc
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestShowData(t *testing.T) {
+
+	services, _ := Table("services")
+	hosts, _ := Table("hosts")
+
+	tests := []struct {
+		t      TableInfo
+		filter string
+		local  bool
+		want   []string
+	}{
+		{services, "", false, nil},
+		{services, "template:^svc", false,
+			[]string{"column:2", "filter:^svc"}},
+		{services, "contacts~ops", false,
+			[]string{"column:6", "filter:ops"}},
+		{services, "template:^svc", true, nil},
+
+		// name, command and svcdesc are url-encoded by the server
+		{services, "name:web", false, nil},
+		{services, "svcdesc~HTTP", false, nil},
+		{services, "name:web,template:^svc", false,
+			[]string{"column:2", "filter:^svc"}},
+		{hosts, "alias:prod", false, nil},
+		{hosts, "alias:prod,ipaddress:^10[.]", false,
+			[]string{"column:3", "filter:^10[.]"}},
+
+		// Only regexes, only at the top level, only portable ones
+		{hosts, "name=web1", false, nil},
+		{hosts, "name:web|hostgroup:x", false, nil},
+		{hosts, "name~web | name~db", false, nil},
+		{hosts, `name:\d+`, false, nil},
+	}
+
+	for _, i := range tests {
+		data, f, err := showData(i.t, Query{Filter: i.filter, Local: i.local})
+		if err != nil {
+			t.Errorf("%s %s: %v", i.t.Name, i.filter, err)
+			continue
+		}
+		if (f == nil) != (i.filter == "") {
+			t.Errorf("%s %s: got filter %v", i.t.Name, i.filter, f)
+		}
+		if !reflect.DeepEqual(data, i.want) {
+			t.Errorf("%s %s: sent %q, want %q", i.t.Name, i.filter, data,
+				i.want)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package nrc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/*
+ * Reconcile brings a folder to a desired state. Desired holds the
+ * records wanted for each managed table, as read from a Snapshot, CSV
+ * files or Nagios configuration. Tables not in desired are left alone
+ * and an empty slice for a table means it should be emptied.
+ *
+ * Records are matched by the table's key fields. Fields empty in the
+ * desired record are cleared.
+ */
+
+// Action is what a Change does
+type Action string
+
+const (
+	ActionAdd    Action = "add"
+	ActionModify Action = "modify"
+	ActionDelete Action = "delete"
+)
+
+// Change is one add, modify or delete request of a Plan
+type Change struct {
+	Action Action
+	Table  string
+	Key    Record            // the key fields, for modify and delete
+	Record Record            // the desired record, for add
+	Old    map[string]string // the current non-empty fields
+	Fields map[string]string // new values for modify, "-" clears a field
+}
+
+// Plan is the list of changes that reconcile a folder, in the order they
+// are sent. Held lists the deletes left out by NeverDelete.
+type Plan struct {
+	Changes []Change
+	Held    []Change
+}
+
+// PlanOptions control how a Plan is made
+type PlanOptions struct {
+	NeverDelete bool // leave records that are not desired in place
+}
+
+// ReconcileOptions control Reconcile
+type ReconcileOptions struct {
+	PlanOptions
+	DryRun bool      // make and print the plan but send nothing
+	Out    io.Writer // if set, the plan is written here before applying
+}
+
+/*
+ * Fetch the managed tables of the client's folder and make the plan that
+ * turns them into desired
+ */
+func MakePlan(ctx context.Context, c *Client, desired map[string][]Record,
+	opts PlanOptions) (*Plan, error) {
+
+	current := make(map[string][]Record)
+	for table := range desired {
+		recs, err := fetchRecords(ctx, c, table)
+		if err != nil {
+			return nil, err
+		}
+		current[table] = recs
+	}
+
+	return ComparePlan(current, desired, opts)
+}
+
+/*
+ * Make the plan that turns current into desired, comparing only the
+ * tables in desired. Adds and modifies come in dependency order followed
+ * by deletes in the reverse order, so nothing is referred to before it
+ * exists or after it is gone.
+ */
+func ComparePlan(current, desired map[string][]Record,
+	opts PlanOptions) (*Plan, error) {
+
+	for table := range desired {
+		if _, ok := Table(table); !ok {
+			return nil, ValidationError{table, nil,
+				fmt.Sprintf("Unknown table '%s'.", table)}
+		}
+	}
+
+	p := &Plan{}
+	deletes := [][]Change{}
+
+	for _, table := range dependencyOrder {
+		want, ok := desired[table]
+		if !ok {
+			continue
+		}
+		t, _ := Table(table)
+
+		have := make(map[string]Record)
+		for _, r := range current[table] {
+			have[recordKey(t, r)] = r
+		}
+
+		seen := make(map[string]bool)
+		for _, r := range want {
+			if r.TableName() != table {
+				return nil, ValidationError{table, nil,
+					fmt.Sprintf("A %s record is in the %s list.",
+						r.TableName(), table)}
+			}
+			k := recordKey(t, r)
+			if seen[k] {
+				return nil, ValidationError{table, t.Keys,
+					fmt.Sprintf("Duplicate %s record %s.", table,
+						recordName(t, r))}
+			}
+			seen[k] = true
+
+			old, exists := have[k]
+			if !exists {
+				p.Changes = append(p.Changes, Change{Action: ActionAdd,
+					Table: table, Record: r})
+				continue
+			}
+
+			fields := make(map[string]string)
+			for _, f := range t.Fields {
+				if t.IsKey(f) {
+					continue
+				}
+				x, _ := old.Field(f)
+				y, _ := r.Field(f)
+				if x == y {
+					continue
+				}
+				if y == "" {
+					y = "-" // nagrestconf clears a field set to a dash
+				}
+				fields[f] = y
+			}
+			if len(fields) > 0 {
+				p.Changes = append(p.Changes, Change{Action: ActionModify,
+					Table: table, Key: keyRecord(t, old), Old: recordMap(old),
+					Fields: fields})
+			}
+		}
+
+		dels := []Change{}
+		for _, r := range current[table] {
+			if seen[recordKey(t, r)] {
+				continue
+			}
+			dels = append(dels, Change{Action: ActionDelete, Table: table,
+				Key: keyRecord(t, r), Old: recordMap(r)})
+		}
+		deletes = append(deletes, dels)
+	}
+
+	for i := len(deletes) - 1; i >= 0; i-- {
+		if opts.NeverDelete {
+			p.Held = append(p.Held, deletes[i]...)
+		} else {
+			p.Changes = append(p.Changes, deletes[i]...)
+		}
+	}
+
+	return p, nil
+}
+
+/*
+ * Return a string identifying r by its key fields
+ */
+func recordKey(t TableInfo, r Record) string {
+
+	vals := []string{}
+	for _, f := range t.Keys {
+		v, _ := r.Field(f)
+		vals = append(vals, v)
+	}
+
+	return strings.Join(vals, "\x00")
+}
+
+/*
+ * Return the non-empty key fields of r for display, web1/PING
+ */
+func recordName(t TableInfo, r Record) string {
+
+	vals := []string{}
+	for _, f := range t.Keys {
+		if v, _ := r.Field(f); v != "" {
+			vals = append(vals, v)
+		}
+	}
+
+	return strings.Join(vals, "/")
+}
+
+/*
+ * Return a record holding just the key fields of r
+ */
+func keyRecord(t TableInfo, r Record) Record {
+
+	k := newRecord(t.Name)
+	for _, f := range t.Keys {
+		v, _ := r.Field(f)
+		k.SetField(f, v)
+	}
+
+	return k
+}
+
+/*
+ * Report whether the plan changes nothing
+ */
+func (p *Plan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+/*
+ * Write the plan as a diff: '+' adds, '~' modifies with old and new
+ * values and '-' deletes, then a summary line
+ */
+func (p *Plan) Write(w io.Writer) error {
+
+	adds, mods, dels := 0, 0, 0
+
+	for _, c := range p.Changes {
+		t, _ := Table(c.Table)
+		switch c.Action {
+		case ActionAdd:
+			adds++
+			fmt.Fprintf(w, "+ %s %s\n", c.Table, recordName(t, c.Record))
+			for _, f := range t.Fields {
+				if v, _ := c.Record.Field(f); v != "" && !t.IsKey(f) {
+					fmt.Fprintf(w, "    %s: %s\n", f, strconv.Quote(v))
+				}
+			}
+		case ActionModify:
+			mods++
+			fmt.Fprintf(w, "~ %s %s\n", c.Table, recordName(t, c.Key))
+			for _, f := range t.Fields {
+				v, ok := c.Fields[f]
+				if !ok {
+					continue
+				}
+				if v == "-" {
+					v = ""
+				}
+				fmt.Fprintf(w, "    %s: %s -> %s\n", f,
+					strconv.Quote(c.Old[f]), strconv.Quote(v))
+			}
+		case ActionDelete:
+			dels++
+			fmt.Fprintf(w, "- %s %s\n", c.Table, recordName(t, c.Key))
+		}
+	}
+	for _, c := range p.Held {
+		t, _ := Table(c.Table)
+		fmt.Fprintf(w, "  %s %s (not deleted, never delete is set)\n",
+			c.Table, recordName(t, c.Key))
+	}
+
+	_, err := fmt.Fprintf(w, "Plan: %d to add, %d to modify, %d to delete.\n",
+		adds, mods, dels)
+
+	return err
+}
+
+/*
+ * Send the plan's changes to the client's folder in order. The first
+ * failure stops the apply; changes before it have been made.
+ */
+func (p *Plan) Apply(ctx context.Context, c *Client) error {
+
+	for _, ch := range p.Changes {
+		t, _ := Table(ch.Table)
+
+		var err error
+		switch ch.Action {
+		case ActionAdd:
+			err = addRecord(ctx, c, ch.Record)
+		case ActionModify:
+			var data []string
+			if data, err = modifyData(ch.Table, ch.Key, ch.Fields); err == nil {
+				_, err = clientOrDefault(c).post(ctx, "",
+					"rest/modify/"+ch.Table, "", ch.Table, data)
+			}
+		case ActionDelete:
+			var data []string
+			if data, err = keyData(ch.Table, ch.Key); err == nil {
+				_, err = clientOrDefault(c).post(ctx, "",
+					"rest/delete/"+ch.Table, "", ch.Table, data)
+			}
+		}
+		if err != nil {
+			name := ""
+			if ch.Action == ActionAdd {
+				name = recordName(t, ch.Record)
+			} else {
+				name = recordName(t, ch.Key)
+			}
+			return fmt.Errorf("%s %s %s: %w", ch.Action, ch.Table, name, err)
+		}
+	}
+
+	return nil
+}
+
+/*
+ * Make a plan for desired, write it to opts.Out and, unless DryRun is
+ * set, apply it. The plan is returned either way.
+ */
+func Reconcile(ctx context.Context, c *Client, desired map[string][]Record,
+	opts ReconcileOptions) (*Plan, error) {
+
+	p, err := MakePlan(ctx, c, desired, opts.PlanOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Out != nil {
+		if err := p.Write(opts.Out); err != nil {
+			return p, err
+		}
+	}
+
+	if opts.DryRun {
+		return p, nil
+	}
+
+	return p, p.Apply(ctx, c)
+}
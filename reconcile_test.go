@@ -0,0 +1,311 @@
+package nrc_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	nrc "github.com/mclarkson/nagrestconf-golib"
+	"github.com/mclarkson/nagrestconf-golib/nrctest"
+)
+
+// A service description that needs url-encoding, as a key field
+const reconcileDesc = "HTTP & HTTPS 100%"
+
+/*
+ * Start a server whose local folder holds the current state
+ */
+func newReconcileServer() *nrctest.Server {
+
+	s := nrctest.NewServer()
+	s.Load("local", "hostgroups",
+		map[string]string{"name": "legacy", "alias": "Legacy"})
+	s.Load("local", "hosts",
+		map[string]string{"name": "web1", "alias": "Old", "ipaddress": "10.0.0.1",
+			"template": "hsttmpl-local"},
+		map[string]string{"name": "web2", "alias": "Two", "ipaddress": "10.0.0.2",
+			"template": "hsttmpl-local", "notes": "remove me"},
+		map[string]string{"name": "old9", "alias": "Nine",
+			"ipaddress": "10.0.0.9", "template": "hsttmpl-local"})
+	s.Load("local", "services",
+		map[string]string{"name": "web1", "template": "svctmpl-local",
+			"command": "check_ping", "svcdesc": "PING"},
+		map[string]string{"name": "web1", "template": "svctmpl-local",
+			"command": "check_http", "svcdesc": reconcileDesc},
+		map[string]string{"name": "old9", "template": "svctmpl-local",
+			"command": "check_ping", "svcdesc": "PING"})
+
+	return s
+}
+
+/*
+ * The desired state: web1 modified, web2's notes cleared, web3 added,
+ * old9 and the legacy hostgroup deleted
+ */
+func reconcileDesired() map[string][]nrc.Record {
+
+	return map[string][]nrc.Record{
+		"hostgroups": {
+			nrc.HostGroup{Name: "linux", Alias: "Linux"},
+		},
+		"hosts": {
+			nrc.Host{Name: "web1", Alias: "New", Ipaddress: "10.0.0.1",
+				Template: "hsttmpl-local"},
+			nrc.Host{Name: "web2", Alias: "Two", Ipaddress: "10.0.0.2",
+				Template: "hsttmpl-local"},
+			nrc.Host{Name: "web3", Alias: "Three", Ipaddress: "10.0.0.3",
+				Template: "hsttmpl-local"},
+		},
+		"services": {
+			nrc.Service{Name: "web1", Template: "svctmpl-local",
+				Command: "check_ping!100", Svcdesc: "PING"},
+			nrc.Service{Name: "web1", Template: "svctmpl-local",
+				Command: "check_http", Svcdesc: reconcileDesc},
+			nrc.Service{Name: "web3", Template: "svctmpl-local",
+				Command: "check_ping", Svcdesc: "PING"},
+		},
+	}
+}
+
+/*
+ * Describe the plan's changes, one "action table name" each
+ */
+func planSteps(p *nrc.Plan) []string {
+
+	steps := []string{}
+	for _, c := range p.Changes {
+		r := c.Key
+		if c.Action == nrc.ActionAdd {
+			r = c.Record
+		}
+		name, _ := r.Field("name")
+		if desc, ok := r.Field("svcdesc"); ok {
+			name += "/" + desc
+		}
+		steps = append(steps, string(c.Action)+" "+c.Table+" "+name)
+	}
+
+	return steps
+}
+
+func TestReconcile(t *testing.T) {
+
+	s := newReconcileServer()
+	defer s.Close()
+	c := s.NrcClient("local")
+	ctx := context.Background()
+
+	var out bytes.Buffer
+	p, err := nrc.Reconcile(ctx, c, reconcileDesired(),
+		nrc.ReconcileOptions{Out: &out})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Adds and modifies in dependency order, then deletes in reverse
+	want := []string{
+		"add hostgroups linux",
+		"modify hosts web1",
+		"modify hosts web2",
+		"add hosts web3",
+		"modify services web1/PING",
+		"add services web3/PING",
+		"delete services old9/PING",
+		"delete hosts old9",
+		"delete hostgroups legacy",
+	}
+	if got := planSteps(p); !reflect.DeepEqual(got, want) {
+		t.Errorf("plan:\n%q\nwant:\n%q", got, want)
+	}
+
+	// Sent in the order planned
+	sent := []string{}
+	for _, i := range s.Requests() {
+		if !strings.HasPrefix(i, "rest/show/") {
+			sent = append(sent, i)
+		}
+	}
+	wantSent := []string{
+		"rest/add/hostgroups", "rest/modify/hosts", "rest/modify/hosts",
+		"rest/add/hosts", "rest/modify/services", "rest/add/services",
+		"rest/delete/services", "rest/delete/hosts", "rest/delete/hostgroups",
+	}
+	if !reflect.DeepEqual(sent, wantSent) {
+		t.Errorf("sent:\n%q\nwant:\n%q", sent, wantSent)
+	}
+
+	// A field empty in the desired record is cleared with '-'
+	for _, ch := range p.Changes {
+		if ch.Action != nrc.ActionModify || ch.Table != "hosts" {
+			continue
+		}
+		if name, _ := ch.Key.Field("name"); name == "web2" {
+			if !reflect.DeepEqual(ch.Fields, map[string]string{"notes": "-"}) {
+				t.Errorf("web2 changes %q, want notes cleared", ch.Fields)
+			}
+		}
+	}
+
+	wantOut := `+ hostgroups linux
+    alias: "Linux"
+~ hosts web1
+    alias: "Old" -> "New"
+~ hosts web2
+    notes: "remove me" -> ""
++ hosts web3
+    alias: "Three"
+    ipaddress: "10.0.0.3"
+    template: "hsttmpl-local"
+~ services web1/PING
+    command: "check_ping" -> "check_ping!100"
++ services web3/PING
+    template: "svctmpl-local"
+    command: "check_ping"
+- services old9/PING
+- hosts old9
+- hostgroups legacy
+Plan: 3 to add, 3 to modify, 3 to delete.
+`
+	if out.String() != wantOut {
+		t.Errorf("plan written as:\n%s\nwant:\n%s", out.String(), wantOut)
+	}
+
+	// The folder now holds exactly what was desired
+	for table, recs := range reconcileDesired() {
+		got := s.Records("local", table)
+		if len(got) != len(recs) {
+			t.Errorf("%s: %d records, want %d", table, len(got), len(recs))
+			continue
+		}
+		for n, r := range recs {
+			want := map[string]string{}
+			tinfo, _ := nrc.Table(table)
+			for _, f := range tinfo.Fields {
+				if v, _ := r.Field(f); v != "" {
+					want[f] = v
+				}
+			}
+			have := map[string]string{}
+			for k, v := range got[n] {
+				if v != "" {
+					have[k] = v
+				}
+			}
+			if !reflect.DeepEqual(have, want) {
+				t.Errorf("%s record %d: %q, want %q", table, n+1, have, want)
+			}
+		}
+	}
+
+	// Reconciling again changes nothing
+	p, err = nrc.MakePlan(ctx, c, reconcileDesired(), nrc.PlanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Empty() {
+		t.Errorf("second plan not empty: %q", planSteps(p))
+	}
+}
+
+func TestReconcileDryRun(t *testing.T) {
+
+	s := newReconcileServer()
+	defer s.Close()
+
+	p, err := nrc.Reconcile(context.Background(), s.NrcClient("local"),
+		reconcileDesired(), nrc.ReconcileOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Changes) != 9 {
+		t.Errorf("dry run planned %d changes, want 9", len(p.Changes))
+	}
+	for _, i := range s.Requests() {
+		if !strings.HasPrefix(i, "rest/show/") {
+			t.Errorf("dry run sent %s", i)
+		}
+	}
+}
+
+func TestReconcileNeverDelete(t *testing.T) {
+
+	s := newReconcileServer()
+	defer s.Close()
+
+	var out bytes.Buffer
+	p, err := nrc.Reconcile(context.Background(), s.NrcClient("local"),
+		reconcileDesired(), nrc.ReconcileOptions{Out: &out,
+			PlanOptions: nrc.PlanOptions{NeverDelete: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range p.Changes {
+		if c.Action == nrc.ActionDelete {
+			t.Errorf("delete planned with NeverDelete: %+v", c)
+		}
+	}
+	held := planSteps(&nrc.Plan{Changes: p.Held})
+	want := []string{"delete services old9/PING", "delete hosts old9",
+		"delete hostgroups legacy"}
+	if !reflect.DeepEqual(held, want) {
+		t.Errorf("held %q, want %q", held, want)
+	}
+	if !strings.Contains(out.String(),
+		"  hosts old9 (not deleted, never delete is set)\n") ||
+		!strings.HasSuffix(out.String(),
+			"Plan: 3 to add, 3 to modify, 0 to delete.\n") {
+		t.Errorf("plan written as:\n%s", out.String())
+	}
+	if n := len(s.Records("local", "hosts")); n != 4 {
+		t.Errorf("folder has %d hosts, want 4 with old9 kept", n)
+	}
+}
+
+func TestComparePlanInvalid(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		desired map[string][]nrc.Record
+	}{
+		{"duplicate", map[string][]nrc.Record{"services": {
+			nrc.Service{Name: "web1", Svcdesc: "PING"},
+			nrc.Service{Name: "web1", Svcdesc: "PING", Command: "x"},
+		}}},
+		{"wrong table", map[string][]nrc.Record{"hosts": {
+			nrc.Service{Name: "web1", Svcdesc: "PING"},
+		}}},
+		{"unknown table", map[string][]nrc.Record{"printers": {}}},
+	}
+
+	for _, i := range tests {
+		_, err := nrc.ComparePlan(nil, i.desired, nrc.PlanOptions{})
+		var ve nrc.ValidationError
+		if !errors.As(err, &ve) {
+			t.Errorf("%s: got %v, want a ValidationError", i.name, err)
+		}
+	}
+}
+
+func TestComparePlanUnmanagedTables(t *testing.T) {
+
+	current := map[string][]nrc.Record{
+		"hosts":    {nrc.Host{Name: "web1"}},
+		"services": {nrc.Service{Name: "web1", Svcdesc: "PING"}},
+	}
+
+	// Services are not in desired so are left alone; an empty hosts
+	// list empties the table
+	p, err := nrc.ComparePlan(current,
+		map[string][]nrc.Record{"hosts": {}}, nrc.PlanOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := planSteps(p); !reflect.DeepEqual(got,
+		[]string{"delete hosts web1"}) {
+		t.Errorf("plan %q, want just the host deleted", got)
+	}
+}
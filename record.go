@@ -0,0 +1,179 @@
+package nrc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Record is implemented by the record type of every table, Host,
+// Service and so on.
+type Record interface {
+	TableName() string
+	Field(name string) (string, bool)
+	Values() []string
+}
+
+// FieldSetter is a pointer to a record, *Host, *Service and so on, whose
+// fields can be set by name.
+type FieldSetter interface {
+	Record
+	SetField(name, value string) bool
+}
+
+/*
+ * Fetch every record of table from the client's folder. Records are
+ * pointers to the table's record type.
+ */
+func fetchRecords(ctx context.Context, c *Client,
+	table string) ([]Record, error) {
+
+	t, ok := Table(table)
+	if !ok {
+		return nil, ValidationError{table, nil,
+			fmt.Sprintf("Unknown table '%s'.", table)}
+	}
+
+	body, err := clientOrDefault(c).get(ctx, "", "rest/show/"+table, "", table,
+		nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var reply [][]map[string]string
+	if err := json.Unmarshal(body, &reply); err != nil {
+		return nil, DecodeError{body, err}
+	}
+
+	out := []Record{}
+	for _, j := range reply {
+		r := newRecord(table)
+		for _, content := range j {
+			for k, val := range content {
+				if t.IsEncoded(k) {
+					val, _ = UrlDecode(val)
+				}
+				r.SetField(k, val)
+			}
+		}
+		out = append(out, r)
+	}
+
+	return out, nil
+}
+
+/*
+ * Return the non-empty fields of record r as a map
+ */
+func recordMap(r Record) map[string]string {
+
+	m := make(map[string]string)
+
+	t, _ := Table(r.TableName())
+	for i, s := range r.Values() {
+		if s != "" {
+			m[t.Fields[i]] = s
+		}
+	}
+
+	return m
+}
+
+/*
+ * Turn a map into "field:value" items in table column order
+ */
+func mapData(t TableInfo, m map[string]string) (data []string) {
+
+	for _, i := range t.Fields {
+		if val, ok := m[i]; ok {
+			data = append(data, i+":"+val)
+		}
+	}
+
+	return data
+}
+
+/*
+ * Build the data for an add request, checking the required fields
+ */
+func addData(table string, r Record) ([]string, error) {
+
+	t, _ := Table(table)
+	m := recordMap(r)
+
+	missing := []string{}
+	for _, i := range t.Required {
+		if m[i] == "" {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) > 0 {
+		txt := fmt.Sprintf("Missing required field(s) for %s: %s.",
+			table, strings.Join(missing, ", "))
+		return nil, ValidationError{table, missing, txt}
+	}
+
+	return mapData(t, m), nil
+}
+
+/*
+ * Build the data identifying the record key. Key fields that are also
+ * required must be set, otherwise at least one key field must be.
+ */
+func keyData(table string, key Record) ([]string, error) {
+
+	t, _ := Table(table)
+	m := recordMap(key)
+
+	keys := make(map[string]string)
+	missing := []string{}
+	for _, i := range t.Keys {
+		if m[i] != "" {
+			keys[i] = m[i]
+		} else if t.IsRequired(i) {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) > 0 || len(keys) == 0 {
+		if len(missing) == 0 {
+			missing = t.Keys
+		}
+		txt := fmt.Sprintf("Missing key field(s) for %s: %s.",
+			table, strings.Join(missing, ", "))
+		return nil, ValidationError{table, missing, txt}
+	}
+
+	return mapData(t, keys), nil
+}
+
+/*
+ * Build the data for a modify request: the key followed by the changes
+ */
+func modifyData(table string, key Record,
+	changes map[string]string) ([]string, error) {
+
+	data, err := keyData(table, key)
+	if err != nil {
+		return nil, err
+	}
+
+	t, _ := Table(table)
+	for i := range changes {
+		if !t.HasField(i) {
+			txt := fmt.Sprintf("Unknown field '%s' for %s.", i, table)
+			return nil, ValidationError{table, []string{i}, txt}
+		}
+		if t.IsKey(i) {
+			txt := fmt.Sprintf("Key field '%s' for %s cannot be modified.",
+				i, table)
+			return nil, ValidationError{table, []string{i}, txt}
+		}
+	}
+	if len(changes) == 0 {
+		txt := fmt.Sprintf("No changes given for %s.", table)
+		return nil, ValidationError{table, nil, txt}
+	}
+
+	return append(data, mapData(t, changes)...), nil
+}
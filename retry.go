@@ -0,0 +1,131 @@
+package nrc
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy controls how a Client retries failed requests. Show and
+// check requests are retried whenever a policy is set; add, modify,
+// delete, apply and restart requests only when RetryWrites is true.
+type RetryPolicy struct {
+	MaxAttempts int           // including the first, 1 or less disables
+	Backoff     time.Duration // delay before the first retry, then doubled
+	MaxBackoff  time.Duration // upper bound on the delay, zero for none
+	Jitter      float64       // fraction of each delay randomised, 0 to 1
+	RetryStatus []int         // defaults to 502, 503 and 504
+	RetryWrites bool          // also retry requests that change things
+
+	// OnRetry, if set, is called before each retry is made
+	OnRetry func(attempt int, delay time.Duration, err error)
+}
+
+var defaultRetryStatus = []int{502, 503, 504}
+
+// The longest delay doubling can reach
+const maxRetryDelay = time.Duration(math.MaxInt64)
+
+/*
+ * Decide whether err is worth another attempt. Transport failures are,
+ * as are the listed status codes. Cancellation is not.
+ */
+func (p *RetryPolicy) retryable(ctx context.Context, err error) bool {
+
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var te TransportError
+	if errors.As(err, &te) {
+		return true
+	}
+
+	var se StatusError
+	if errors.As(err, &se) {
+		codes := p.RetryStatus
+		if codes == nil {
+			codes = defaultRetryStatus
+		}
+		for _, i := range codes {
+			if i == se.StatusCode {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+/*
+ * Return the delay before retry number n, counting from 1
+ */
+func (p *RetryPolicy) delay(n int) time.Duration {
+
+	// With no MaxBackoff the delay still stops short of overflowing
+	limit := p.MaxBackoff
+	if limit <= 0 {
+		limit = maxRetryDelay
+	}
+
+	d := p.Backoff
+	for i := 1; i < n && d < limit; i++ {
+		if d > limit/2 {
+			d = limit
+			break
+		}
+		d *= 2
+	}
+	if d > limit {
+		d = limit
+	}
+
+	if p.Jitter > 0 {
+		j := p.Jitter
+		if j > 1 {
+			j = 1
+		}
+		d -= time.Duration(rand.Float64() * j * float64(d))
+	}
+
+	return d
+}
+
+/*
+ * Run send, retrying it as the policy allows. A nil policy sends once.
+ */
+func (p *RetryPolicy) run(ctx context.Context, write bool,
+	send func() ([]byte, error)) ([]byte, error) {
+
+	body, err := send()
+
+	if p == nil || (write && !p.RetryWrites) {
+		return body, err
+	}
+
+	for attempt := 1; err != nil && attempt < p.MaxAttempts; attempt++ {
+
+		if !p.retryable(ctx, err) {
+			break
+		}
+
+		d := p.delay(attempt)
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, d, err)
+		}
+
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, err
+		case <-t.C:
+		}
+
+		body, err = send()
+	}
+
+	return body, err
+}
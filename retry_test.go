@@ -0,0 +1,219 @@
+package nrc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+
+	p := RetryPolicy{Backoff: 100 * time.Millisecond}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond,
+		400 * time.Millisecond, 800 * time.Millisecond}
+	for n, d := range want {
+		if got := p.delay(n + 1); got != d {
+			t.Errorf("delay(%d) = %v, want %v", n+1, got, d)
+		}
+	}
+
+	p.MaxBackoff = 300 * time.Millisecond
+	for n, d := range []time.Duration{100 * time.Millisecond,
+		200 * time.Millisecond, 300 * time.Millisecond,
+		300 * time.Millisecond} {
+		if got := p.delay(n + 1); got != d {
+			t.Errorf("capped delay(%d) = %v, want %v", n+1, got, d)
+		}
+	}
+}
+
+func TestRetryDelayNoOverflow(t *testing.T) {
+
+	// Doubling a second overflows a Duration after about 34 attempts
+	p := RetryPolicy{Backoff: time.Second}
+
+	last := time.Duration(0)
+	for n := 1; n <= 200; n++ {
+		d := p.delay(n)
+		if d < last {
+			t.Fatalf("delay(%d) = %v, less than delay(%d) = %v", n, d,
+				n-1, last)
+		}
+		last = d
+	}
+	if last != maxRetryDelay {
+		t.Errorf("delay(200) = %v, want %v", last, maxRetryDelay)
+	}
+
+	p.MaxBackoff = time.Minute
+	if d := p.delay(200); d != time.Minute {
+		t.Errorf("capped delay(200) = %v, want %v", d, time.Minute)
+	}
+}
+
+func TestRetryDelayJitter(t *testing.T) {
+
+	p := RetryPolicy{Backoff: time.Second, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		if d := p.delay(1); d < 500*time.Millisecond || d > time.Second {
+			t.Fatalf("delay with jitter 0.5 = %v, want 500ms to 1s", d)
+		}
+	}
+}
+
+/*
+ * Start a server that answers the first failures requests with status
+ * and later ones with an empty table, counting every request
+ */
+func newFailingServer(t *testing.T, failures int32, status int,
+	calls *int32) *httptest.Server {
+
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(calls, 1) <= failures {
+				w.WriteHeader(status)
+				w.Write([]byte(`["ERROR: try again."]`))
+				return
+			}
+			w.Write([]byte("[]"))
+		}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func retryClient(srv *httptest.Server, p *RetryPolicy) *Client {
+	c := NewClient(srv.URL, "", "", "local")
+	c.Retry = p
+	return c
+}
+
+func TestRetryRecovers(t *testing.T) {
+
+	var calls int32
+	srv := newFailingServer(t, 3, 503, &calls)
+
+	retries := []int{}
+	c := retryClient(srv, &RetryPolicy{MaxAttempts: 4,
+		Backoff: time.Millisecond,
+		OnRetry: func(attempt int, _ time.Duration, err error) {
+			retries = append(retries, attempt)
+			if !errors.As(err, &StatusError{}) {
+				t.Errorf("retry %d after %v, want a StatusError", attempt,
+					err)
+			}
+		}})
+
+	err := c.Hosts().GetContext(context.Background(), "", "rest/show/hosts",
+		"", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 4 {
+		t.Errorf("server saw %d requests, want 4", calls)
+	}
+	if len(retries) != 3 || retries[0] != 1 || retries[2] != 3 {
+		t.Errorf("OnRetry saw attempts %v, want [1 2 3]", retries)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+
+	var calls int32
+	srv := newFailingServer(t, 5, 502, &calls)
+	c := retryClient(srv, &RetryPolicy{MaxAttempts: 3,
+		Backoff: time.Millisecond})
+
+	err := c.Hosts().GetContext(context.Background(), "", "rest/show/hosts",
+		"", nil)
+	var se StatusError
+	if !errors.As(err, &se) || se.StatusCode != 502 {
+		t.Errorf("got %v, want the last 502", err)
+	}
+	if calls != 3 {
+		t.Errorf("server saw %d requests, want 3", calls)
+	}
+}
+
+func TestRetryStatusNotListed(t *testing.T) {
+
+	var calls int32
+	srv := newFailingServer(t, 1, 500, &calls)
+	c := retryClient(srv, &RetryPolicy{MaxAttempts: 3,
+		Backoff: time.Millisecond})
+
+	if err := c.Hosts().GetContext(context.Background(), "",
+		"rest/show/hosts", "", nil); err == nil {
+		t.Error("500 was retried by default")
+	}
+	if calls != 1 {
+		t.Errorf("server saw %d requests, want 1", calls)
+	}
+
+	calls = 0
+	c.Retry.RetryStatus = []int{500}
+	if err := c.Hosts().GetContext(context.Background(), "",
+		"rest/show/hosts", "", nil); err != nil {
+		t.Errorf("500 in RetryStatus: %v", err)
+	}
+}
+
+func TestRetryWrites(t *testing.T) {
+
+	var calls int32
+	srv := newFailingServer(t, 1, 503, &calls)
+	c := retryClient(srv, &RetryPolicy{MaxAttempts: 3,
+		Backoff: time.Millisecond})
+	ctx := context.Background()
+	data := []string{"name:web1"}
+
+	if err := c.Hosts().PostContext(ctx, "", "rest/delete/hosts", "",
+		data); err == nil {
+		t.Error("write retried without RetryWrites")
+	}
+	if calls != 1 {
+		t.Errorf("server saw %d requests, want 1", calls)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	c.Retry.RetryWrites = true
+	if err := c.Hosts().PostContext(ctx, "", "rest/delete/hosts", "",
+		data); err != nil {
+		t.Errorf("write with RetryWrites: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("server saw %d requests, want 2", calls)
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+
+	var calls int32
+	srv := newFailingServer(t, 10, 503, &calls)
+	c := retryClient(srv, &RetryPolicy{MaxAttempts: 10,
+		Backoff: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.Retry.OnRetry = func(int, time.Duration, error) { cancel() }
+
+	done := make(chan error)
+	go func() {
+		done <- c.Hosts().GetContext(ctx, "", "rest/show/hosts", "", nil)
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("cancelled request succeeded")
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("cancel did not stop the retry delay")
+	}
+	if calls != 1 {
+		t.Errorf("server saw %d requests, want 1", calls)
+	}
+}
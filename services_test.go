@@ -0,0 +1,80 @@
+package nrc
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+// The size of a large installation
+const benchServices = 50000
+
+func benchmarkServices() Services {
+
+	descs := []string{"PING", "HTTP", "HTTPS", "SSH", "Disk /", "Load",
+		"Memory", "NTP", "SMTP", "DNS"}
+
+	h := Services{}
+	for i := 0; i < benchServices; i++ {
+		h.services = append(h.services, Service{
+			Name:            fmt.Sprintf("web%05d", i/len(descs)),
+			Template:        "svctmpl-local",
+			Command:         "check_" + descs[i%len(descs)] + "!80!5",
+			Svcdesc:         descs[i%len(descs)],
+			Svcgroup:        "webservers",
+			Contacts:        "ops",
+			Contactgroups:   "admins",
+			Freshnessthresh: fmt.Sprintf("%d", i%600),
+			Disable:         fmt.Sprintf("%d", i%2),
+		})
+	}
+
+	return h
+}
+
+/*
+ * Send stdout to /dev/null while the benchmark runs
+ */
+func discardStdout(b *testing.B) {
+
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = null
+	b.Cleanup(func() {
+		os.Stdout = stdout
+		null.Close()
+	})
+}
+
+func BenchmarkServicesWrite(b *testing.B) {
+
+	h := benchmarkServices()
+	discardStdout(b)
+
+	b.Run("text", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			h.Show(false, "")
+		}
+	})
+	b.Run("json", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			h.ShowJson(false, false, "")
+		}
+	})
+}
+
+func BenchmarkServicesFilter(b *testing.B) {
+
+	h := benchmarkServices()
+
+	for i := 0; i < b.N; i++ {
+		g := h
+		g.filterServices("name:web0.*5,svcdesc:HTTP")
+		if len(g.services) == 0 {
+			b.Fatal("filter matched nothing")
+		}
+	}
+}
@@ -1,87 +1,356 @@
+// Code generated by internal/gen from schema.json; DO NOT EDIT.
+
 package nrc
 
 import (
-	"bytes"
-	"crypto/tls"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
-	"reflect"
-	"regexp"
+	"io"
+	"os"
 	"sort"
-	"strings"
 )
 
-type servicetemplate struct {
-	name                  string
-	use                   string
-	contacts              string
-	contactgroups         string
-	notifopts             string
-	checkinterval         string
-	normchecki            string
-	retryinterval         string
-	notifinterval         string
-	notifperiod           string
-	disable               string
-	checkperiod           string
-	maxcheckattempts      string
-	freshnessthresh       string
-	activechecks          string
-	customvars            string
-	isvolatile            string
-	initialstate          string
-	passivechecks         string
-	obsessoverservice     string
-	manfreshnessthresh    string
-	checkfreshness        string
-	eventhandler          string
-	eventhandlerenabled   string
-	lowflapthresh         string
-	highflapthresh        string
-	flapdetectionenabled  string
-	flapdetectionoptions  string
-	processperfdata       string
-	retainstatusinfo      string
-	retainnonstatusinfo   string
-	firstnotifdelay       string
-	notifications_enabled string
-	stalkingoptions       string
-	notes                 string
-	notes_url             string
-	action_url            string
-	icon_image            string
-	icon_image_alt        string
-	vrml_image            string
-	statusmap_image       string
-	coords2d              string
-	coords3d              string
+// ServiceTemplate is one record of the nagrestconf servicetemplates table.
+type ServiceTemplate struct {
+	Name                 string `json:"name,omitempty"`
+	Use                  string `json:"use,omitempty"`
+	Contacts             string `json:"contacts,omitempty"`
+	Contactgroups        string `json:"contactgroups,omitempty"`
+	Notifopts            string `json:"notifopts,omitempty"`
+	Checkinterval        string `json:"checkinterval,omitempty"`
+	Normchecki           string `json:"normchecki,omitempty"`
+	Retryinterval        string `json:"retryinterval,omitempty"`
+	Notifinterval        string `json:"notifinterval,omitempty"`
+	Notifperiod          string `json:"notifperiod,omitempty"`
+	Disable              string `json:"disable,omitempty"`
+	Checkperiod          string `json:"checkperiod,omitempty"`
+	Maxcheckattempts     string `json:"maxcheckattempts,omitempty"`
+	Freshnessthresh      string `json:"freshnessthresh,omitempty"`
+	Activechecks         string `json:"activechecks,omitempty"`
+	Customvars           string `json:"customvars,omitempty"`
+	Isvolatile           string `json:"isvolatile,omitempty"`
+	Initialstate         string `json:"initialstate,omitempty"`
+	Passivechecks        string `json:"passivechecks,omitempty"`
+	Obsessoverservice    string `json:"obsessoverservice,omitempty"`
+	Manfreshnessthresh   string `json:"manfreshnessthresh,omitempty"`
+	Checkfreshness       string `json:"checkfreshness,omitempty"`
+	Eventhandler         string `json:"eventhandler,omitempty"`
+	Eventhandlerenabled  string `json:"eventhandlerenabled,omitempty"`
+	Lowflapthresh        string `json:"lowflapthresh,omitempty"`
+	Highflapthresh       string `json:"highflapthresh,omitempty"`
+	Flapdetectionenabled string `json:"flapdetectionenabled,omitempty"`
+	Flapdetectionoptions string `json:"flapdetectionoptions,omitempty"`
+	Processperfdata      string `json:"processperfdata,omitempty"`
+	Retainstatusinfo     string `json:"retainstatusinfo,omitempty"`
+	Retainnonstatusinfo  string `json:"retainnonstatusinfo,omitempty"`
+	Firstnotifdelay      string `json:"firstnotifdelay,omitempty"`
+	NotificationsEnabled string `json:"notifications_enabled,omitempty"`
+	Stalkingoptions      string `json:"stalkingoptions,omitempty"`
+	Notes                string `json:"notes,omitempty"`
+	NotesUrl             string `json:"notes_url,omitempty"`
+	ActionUrl            string `json:"action_url,omitempty"`
+	IconImage            string `json:"icon_image,omitempty"`
+	IconImageAlt         string `json:"icon_image_alt,omitempty"`
+	VrmlImage            string `json:"vrml_image,omitempty"`
+	StatusmapImage       string `json:"statusmap_image,omitempty"`
+	Coords2d             string `json:"coords2d,omitempty"`
+	Coords3d             string `json:"coords3d,omitempty"`
+}
+
+func (r ServiceTemplate) TableName() string {
+	return "servicetemplates"
+}
+
+/*
+ * Return the value of the named field and whether the table has it
+ */
+func (r ServiceTemplate) Field(name string) (string, bool) {
+	switch name {
+	case "name":
+		return r.Name, true
+	case "use":
+		return r.Use, true
+	case "contacts":
+		return r.Contacts, true
+	case "contactgroups":
+		return r.Contactgroups, true
+	case "notifopts":
+		return r.Notifopts, true
+	case "checkinterval":
+		return r.Checkinterval, true
+	case "normchecki":
+		return r.Normchecki, true
+	case "retryinterval":
+		return r.Retryinterval, true
+	case "notifinterval":
+		return r.Notifinterval, true
+	case "notifperiod":
+		return r.Notifperiod, true
+	case "disable":
+		return r.Disable, true
+	case "checkperiod":
+		return r.Checkperiod, true
+	case "maxcheckattempts":
+		return r.Maxcheckattempts, true
+	case "freshnessthresh":
+		return r.Freshnessthresh, true
+	case "activechecks":
+		return r.Activechecks, true
+	case "customvars":
+		return r.Customvars, true
+	case "isvolatile":
+		return r.Isvolatile, true
+	case "initialstate":
+		return r.Initialstate, true
+	case "passivechecks":
+		return r.Passivechecks, true
+	case "obsessoverservice":
+		return r.Obsessoverservice, true
+	case "manfreshnessthresh":
+		return r.Manfreshnessthresh, true
+	case "checkfreshness":
+		return r.Checkfreshness, true
+	case "eventhandler":
+		return r.Eventhandler, true
+	case "eventhandlerenabled":
+		return r.Eventhandlerenabled, true
+	case "lowflapthresh":
+		return r.Lowflapthresh, true
+	case "highflapthresh":
+		return r.Highflapthresh, true
+	case "flapdetectionenabled":
+		return r.Flapdetectionenabled, true
+	case "flapdetectionoptions":
+		return r.Flapdetectionoptions, true
+	case "processperfdata":
+		return r.Processperfdata, true
+	case "retainstatusinfo":
+		return r.Retainstatusinfo, true
+	case "retainnonstatusinfo":
+		return r.Retainnonstatusinfo, true
+	case "firstnotifdelay":
+		return r.Firstnotifdelay, true
+	case "notifications_enabled":
+		return r.NotificationsEnabled, true
+	case "stalkingoptions":
+		return r.Stalkingoptions, true
+	case "notes":
+		return r.Notes, true
+	case "notes_url":
+		return r.NotesUrl, true
+	case "action_url":
+		return r.ActionUrl, true
+	case "icon_image":
+		return r.IconImage, true
+	case "icon_image_alt":
+		return r.IconImageAlt, true
+	case "vrml_image":
+		return r.VrmlImage, true
+	case "statusmap_image":
+		return r.StatusmapImage, true
+	case "coords2d":
+		return r.Coords2d, true
+	case "coords3d":
+		return r.Coords3d, true
+	}
+	return "", false
+}
+
+/*
+ * Set the named field, returning false if the table has no such field
+ */
+func (r *ServiceTemplate) SetField(name, value string) bool {
+	switch name {
+	case "name":
+		r.Name = value
+	case "use":
+		r.Use = value
+	case "contacts":
+		r.Contacts = value
+	case "contactgroups":
+		r.Contactgroups = value
+	case "notifopts":
+		r.Notifopts = value
+	case "checkinterval":
+		r.Checkinterval = value
+	case "normchecki":
+		r.Normchecki = value
+	case "retryinterval":
+		r.Retryinterval = value
+	case "notifinterval":
+		r.Notifinterval = value
+	case "notifperiod":
+		r.Notifperiod = value
+	case "disable":
+		r.Disable = value
+	case "checkperiod":
+		r.Checkperiod = value
+	case "maxcheckattempts":
+		r.Maxcheckattempts = value
+	case "freshnessthresh":
+		r.Freshnessthresh = value
+	case "activechecks":
+		r.Activechecks = value
+	case "customvars":
+		r.Customvars = value
+	case "isvolatile":
+		r.Isvolatile = value
+	case "initialstate":
+		r.Initialstate = value
+	case "passivechecks":
+		r.Passivechecks = value
+	case "obsessoverservice":
+		r.Obsessoverservice = value
+	case "manfreshnessthresh":
+		r.Manfreshnessthresh = value
+	case "checkfreshness":
+		r.Checkfreshness = value
+	case "eventhandler":
+		r.Eventhandler = value
+	case "eventhandlerenabled":
+		r.Eventhandlerenabled = value
+	case "lowflapthresh":
+		r.Lowflapthresh = value
+	case "highflapthresh":
+		r.Highflapthresh = value
+	case "flapdetectionenabled":
+		r.Flapdetectionenabled = value
+	case "flapdetectionoptions":
+		r.Flapdetectionoptions = value
+	case "processperfdata":
+		r.Processperfdata = value
+	case "retainstatusinfo":
+		r.Retainstatusinfo = value
+	case "retainnonstatusinfo":
+		r.Retainnonstatusinfo = value
+	case "firstnotifdelay":
+		r.Firstnotifdelay = value
+	case "notifications_enabled":
+		r.NotificationsEnabled = value
+	case "stalkingoptions":
+		r.Stalkingoptions = value
+	case "notes":
+		r.Notes = value
+	case "notes_url":
+		r.NotesUrl = value
+	case "action_url":
+		r.ActionUrl = value
+	case "icon_image":
+		r.IconImage = value
+	case "icon_image_alt":
+		r.IconImageAlt = value
+	case "vrml_image":
+		r.VrmlImage = value
+	case "statusmap_image":
+		r.StatusmapImage = value
+	case "coords2d":
+		r.Coords2d = value
+	case "coords3d":
+		r.Coords3d = value
+	default:
+		return false
+	}
+	return true
+}
+
+/*
+ * Return the field values in table column order
+ */
+func (r ServiceTemplate) Values() []string {
+	return []string{
+		r.Name,
+		r.Use,
+		r.Contacts,
+		r.Contactgroups,
+		r.Notifopts,
+		r.Checkinterval,
+		r.Normchecki,
+		r.Retryinterval,
+		r.Notifinterval,
+		r.Notifperiod,
+		r.Disable,
+		r.Checkperiod,
+		r.Maxcheckattempts,
+		r.Freshnessthresh,
+		r.Activechecks,
+		r.Customvars,
+		r.Isvolatile,
+		r.Initialstate,
+		r.Passivechecks,
+		r.Obsessoverservice,
+		r.Manfreshnessthresh,
+		r.Checkfreshness,
+		r.Eventhandler,
+		r.Eventhandlerenabled,
+		r.Lowflapthresh,
+		r.Highflapthresh,
+		r.Flapdetectionenabled,
+		r.Flapdetectionoptions,
+		r.Processperfdata,
+		r.Retainstatusinfo,
+		r.Retainnonstatusinfo,
+		r.Firstnotifdelay,
+		r.NotificationsEnabled,
+		r.Stalkingoptions,
+		r.Notes,
+		r.NotesUrl,
+		r.ActionUrl,
+		r.IconImage,
+		r.IconImageAlt,
+		r.VrmlImage,
+		r.StatusmapImage,
+		r.Coords2d,
+		r.Coords3d,
+	}
 }
 
 type Servicetemplates struct {
-	servicetemplates []servicetemplate
-	username         string
-	password         string
+	servicetemplates []ServiceTemplate
+	client           *Client
+	columns          []string
+	order            []sortKey
 }
 
 func (h Servicetemplates) RequiredOptions() []string {
-	return []string{"name", "checkinterval", "retryinterval", "notifinterval", "notifperiod", "checkperiod", "maxcheckattempts"}
+	t, _ := Table("servicetemplates")
+	return t.Required
 }
 
 func (h *Servicetemplates) Options() (arr []string) {
 
-	n := reflect.TypeOf(h.servicetemplates).Elem().NumField()
-	for i := 0; i < n; i++ {
-		f := reflect.TypeOf(h.servicetemplates).Elem().Field(i)
-		arr = append(arr, f.Name)
-	}
+	t, _ := Table("servicetemplates")
+	arr = t.Fields
 
 	sort.Strings(arr)
 
 	return arr
 }
 
+/*
+ * Return a copy of the records fetched by Get, in the order set by
+ * SortBy
+ */
+func (h Servicetemplates) Records() []ServiceTemplate {
+	return h.sorted()
+}
+
+/*
+ * Return a copy of the records, sorted if SortBy was used
+ */
+func (h Servicetemplates) sorted() []ServiceTemplate {
+
+	r := append([]ServiceTemplate(nil), h.servicetemplates...)
+
+	if h.order != nil {
+		sort.SliceStable(r, func(i, j int) bool {
+			return compareRecords(h.order, r[i], r[j]) < 0
+		})
+	}
+
+	return r
+}
+
 func (h Servicetemplates) OptionsJson() (s string) {
 
 	f := h.Options()
@@ -97,360 +366,290 @@ func (h Servicetemplates) OptionsJson() (s string) {
 	return s
 }
 
-func (h *Servicetemplates) filterServicetemplates(filter string) {
+/*
+ * Keep only the records that pass the filter expression. Malformed
+ * filters and unknown field names are errors.
+ */
+func (h *Servicetemplates) filterServicetemplates(filter string) error {
 
-	f := NewFilter(filter)
+	f, err := CompileFilter(filter)
+	if err != nil {
+		return err
+	}
+	if err := f.Check(h.Options(), "servicetemplates"); err != nil {
+		return err
+	}
 
-	newh := []servicetemplate{}
+	newh := []ServiceTemplate{}
 
 	for _, k := range h.servicetemplates {
-		t := &k // Must be a pointer or interface for reflection
-		foundCount := 0
-		for i := 0; i < len(f.names); i++ {
-			_, found := reflect.TypeOf(t).Elem().FieldByName(f.names[i])
-			if found == true {
-				val := reflect.ValueOf(t).Elem().FieldByName(f.names[i])
-				userRegx, _ := UrlDecodeForce(f.regex[i])
-				regex := regexp.MustCompile(userRegx)
-				if regex.MatchString(val.String()) {
-					foundCount += 1
-					continue
-				}
-				/*
-					val := reflect.ValueOf(t).Elem().FieldByName(f.names[i])
-					if val.String() == f.regex[i] {
-						foundCount += 1
-						continue
-					}
-				*/
-			}
-		}
-		if foundCount == len(f.names) {
+		if f.Match(k) {
 			newh = append(newh, k)
 		}
 	}
 
 	// Replace the list we got with this filtered list
 	h.servicetemplates = newh
+
+	return nil
 }
 
 func (h Servicetemplates) Show(brief bool, filter string) {
-
-	if filter != "" {
-		h.filterServicetemplates(filter)
+	if err := h.WriteText(os.Stdout, brief, filter); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
 	}
+}
 
-	var ind4 = "    " // big indent
-
-	fmt.Printf("\n")
-	for _, r := range h.servicetemplates {
-		t := &r // Reflection is only allowed on ptr or interface
-		n := reflect.TypeOf(t).Elem().NumField()
-		for i := 0; i < n; i++ {
-			f := reflect.TypeOf(t).Elem().Field(i)
-			g := reflect.ValueOf(t).Elem().Field(i)
-			if brief == true || (brief == false && g.String() != "") {
-				fmt.Printf("%s%s:%s\n",
-					ind4, f.Name, g)
-			}
-		}
-		fmt.Printf("\n")
-	}
+/*
+ * Write the records as indented field:value blocks
+ */
+func (h Servicetemplates) WriteText(w io.Writer, brief bool, filter string) error {
+	return h.Write(w, TextFormatter{ShowEmpty: brief}, filter)
 }
 
 func (h Servicetemplates) ShowJson(newline, brief bool, filter string) {
+	if err := h.WriteJSON(os.Stdout, newline, brief, filter); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+	}
+}
+
+/*
+ * Write the records as a json array of objects. Newline set means
+ * everything goes on one line.
+ */
+func (h Servicetemplates) WriteJSON(w io.Writer, newline, brief bool,
+	filter string) error {
+	return h.Write(w, JSONFormatter{Compact: newline, ShowEmpty: brief}, filter)
+}
+
+/*
+ * Write the records, after filtering and sorting, using formatter f
+ */
+func (h Servicetemplates) Write(w io.Writer, f Formatter, filter string) error {
 
 	if filter != "" {
-		h.filterServicetemplates(filter)
+		if err := h.filterServicetemplates(filter); err != nil {
+			return err
+		}
 	}
 
-	var nl = ""   // newline
-	var ind4 = "" // big indent
-	var ind2 = "" // small indent
+	t, _ := Table("servicetemplates")
 
-	if newline == false {
-		nl = "\n"
-		ind4 = "    "
-		ind2 = "  "
+	rows := make([][]string, 0, len(h.servicetemplates))
+	for _, r := range h.sorted() {
+		rows = append(rows, r.Values())
 	}
 
-	fmt.Printf("[")
-	objcomma := ""
-	for _, r := range h.servicetemplates {
-		comma := ""
-		nli := ""
-		fmt.Printf("%s%s%s{%s", objcomma, nl, ind2, nl)
-		t := &r // Reflection is only allowed on ptr or interface
-		n := reflect.TypeOf(t).Elem().NumField()
-		for i := 0; i < n; i++ {
-			f := reflect.TypeOf(t).Elem().Field(i)
-			g := reflect.ValueOf(t).Elem().Field(i)
-			if brief == true || (brief == false && g.String() != "") {
-				fmt.Printf("%s%s%s\"%s\":\"%s\"",
-					comma, nli, ind4, f.Name, g)
-				comma = ","
-				nli = nl
-			}
+	if h.columns != nil {
+		fields, rows, err := selectColumns(t.Fields, rows, h.columns)
+		if err != nil {
+			return err
 		}
-		fmt.Printf("%s%s}", nl, ind2)
-		objcomma = ","
+		return f.Format(w, fields, rows)
 	}
-	fmt.Printf("%s]\n", nl)
-}
 
-func NewNrcServicetemplates(username, password string) *Servicetemplates {
-	h := &Servicetemplates{}
-	h.username = username
-	h.password = password
-	return h
+	return f.Format(w, t.Fields, rows)
 }
 
 /*
- * Send HTTP GET request
+ * Choose the fields, and their order, used by every output format.
+ * No columns means all fields again.
  */
-func (h *Servicetemplates) Get(url, endpoint, folder string, data []string) (e error) {
+func (h *Servicetemplates) Select(columns ...string) error {
 
-	// accept bad certs
-	tr := &http.Transport{
-		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
-		DisableKeepAlives: true,
+	if len(columns) == 0 {
+		h.columns = nil
+		return nil
 	}
-	client := &http.Client{Transport: tr}
-	// Not available in Go<1.3
-	//client.Timeout = 8 * 1e9
 
-	for strings.HasSuffix(url, "/") {
-		url = strings.TrimSuffix(url, "/")
-	}
-	for strings.HasSuffix(endpoint, "/") {
-		url = strings.TrimSuffix(endpoint, "/")
+	if err := checkColumns(h.Options(), "servicetemplates", columns); err != nil {
+		return err
 	}
+	h.columns = append([]string{}, columns...)
 
-	// Construct url, http://1.2.3.4/rest/show/hosts?json={"folder":"local",...}
-	fullUrl := url + "/" + endpoint + "?json={\"folder\":\"" + folder + "\""
-	dataStr, err := FormatData(data, "services")
-	if err != nil {
-		txt := fmt.Sprintf("Could not format data. Check the '-d' option.")
-		return HttpError{txt}
-	}
-	if dataStr != "" {
-		fullUrl += "," + dataStr
-	}
-	fullUrl += "}"
+	return nil
+}
 
-	//fmt.Printf("URL=%s\n", fullUrl)
+/*
+ * Set the order of the records returned by Records and written by every
+ * output format. Records are sorted on the first field, then the second
+ * and so on, with runs of digits compared as numbers so web9 comes
+ * before web10. Prefix a field with '-' to sort it descending. No fields
+ * means the order the server returned.
+ */
+func (h *Servicetemplates) SortBy(fields ...string) error {
 
-	//fmt.Printf("%s\n", url+"/"+endpoint)
-	//resp, err := client.Get(fullUrl)
-	req, err := http.NewRequest("GET", fullUrl, nil)
-	if len(h.username) > 0 {
-		req.SetBasicAuth(h.username, h.password)
+	if len(fields) == 0 {
+		h.order = nil
+		return nil
 	}
-	resp, err := client.Do(req)
+
+	order, err := parseSort(h.Options(), "servicetemplates", fields)
 	if err != nil {
-		txt := fmt.Sprintf("Could not send REST request ('%s').", err.Error())
-		return HttpError{txt}
+		return err
 	}
+	h.order = order
 
-	defer resp.Body.Close()
+	return nil
+}
 
-	var body []byte
-	if b, err := ioutil.ReadAll(resp.Body); err != nil {
-		txt := fmt.Sprintf("Error reading Body ('%s').", err.Error())
-		return HttpError{txt}
-	} else {
-		body = b
-	}
+// NewNrcServicetemplates returns a query that skips server certificate
+// verification.
+//
+// Deprecated: Use NewClient, with SetTLS for a CA bundle or pinned
+// fingerprint, and its Servicetemplates method.
+func NewNrcServicetemplates(username, password string) *Servicetemplates {
+	return newInsecureClient(username, password).Servicetemplates()
+}
 
-	if resp.StatusCode != 200 {
+/*
+ * Create a Servicetemplates query that sends its requests through c
+ */
+func (c *Client) Servicetemplates() *Servicetemplates {
+	h := &Servicetemplates{}
+	h.client = c
+	return h
+}
 
-		response, _ := UrlDecode(string(body))
-		txt := fmt.Sprintf("Status (%d): %s", resp.StatusCode, response)
-		return HttpError{txt}
+/*
+ * Send HTTP GET request
+ */
+func (h *Servicetemplates) Get(url, endpoint, folder string, data []string) (e error) {
+	return h.GetContext(context.Background(), url, endpoint, folder, data)
+}
 
-	} else {
+func (h *Servicetemplates) GetContext(ctx context.Context, url, endpoint,
+	folder string, data []string) (e error) {
 
-		var generic interface{}
-		if err := json.Unmarshal(body, &generic); err != nil {
-			txt := fmt.Sprintf("Status (%d) Error decoding JSON (%s).",
-				resp.StatusCode, err.Error())
-			return HttpError{txt}
-		}
-		genericReply := generic.([]interface{})
-
-		//fmt.Printf("%s\n", body)
-
-		for _, j := range genericReply {
-			servicetemplate := servicetemplate{}
-			for _, j2 := range j.([]interface{}) {
-				content := j2.(map[string]interface{})
-				for k, v := range content {
-					switch k {
-					case "name":
-						servicetemplate.name = v.(string)
-					case "use":
-						servicetemplate.use = v.(string)
-					case "contacts":
-						servicetemplate.contacts = v.(string)
-					case "contactgroups":
-						servicetemplate.contactgroups = v.(string)
-					case "notifopts":
-						servicetemplate.notifopts = v.(string)
-					case "checkinterval":
-						servicetemplate.checkinterval = v.(string)
-					case "normchecki":
-						servicetemplate.normchecki = v.(string)
-					case "retryinterval":
-						servicetemplate.retryinterval = v.(string)
-					case "notifinterval":
-						servicetemplate.notifinterval = v.(string)
-					case "notifperiod":
-						servicetemplate.notifperiod = v.(string)
-					case "disable":
-						servicetemplate.disable = v.(string)
-					case "checkperiod":
-						servicetemplate.checkperiod = v.(string)
-					case "maxcheckattempts":
-						servicetemplate.maxcheckattempts = v.(string)
-					case "freshnessthresh":
-						servicetemplate.freshnessthresh = v.(string)
-					case "activechecks":
-						servicetemplate.activechecks = v.(string)
-					case "customvars":
-						servicetemplate.customvars = v.(string)
-					case "isvolatile":
-						servicetemplate.isvolatile = v.(string)
-					case "initialstate":
-						servicetemplate.initialstate = v.(string)
-					case "passivechecks":
-						servicetemplate.passivechecks = v.(string)
-					case "obsessoverservice":
-						servicetemplate.obsessoverservice = v.(string)
-					case "manfreshnessthresh":
-						servicetemplate.manfreshnessthresh = v.(string)
-					case "checkfreshness":
-						servicetemplate.checkfreshness = v.(string)
-					case "eventhandler":
-						servicetemplate.eventhandler = v.(string)
-					case "eventhandlerenabled":
-						servicetemplate.eventhandlerenabled = v.(string)
-					case "lowflapthresh":
-						servicetemplate.lowflapthresh = v.(string)
-					case "highflapthresh":
-						servicetemplate.highflapthresh = v.(string)
-					case "flapdetectionenabled":
-						servicetemplate.flapdetectionenabled = v.(string)
-					case "flapdetectionoptions":
-						servicetemplate.flapdetectionoptions = v.(string)
-					case "processperfdata":
-						servicetemplate.processperfdata = v.(string)
-					case "retainstatusinfo":
-						servicetemplate.retainstatusinfo = v.(string)
-					case "retainnonstatusinfo":
-						servicetemplate.retainnonstatusinfo = v.(string)
-					case "firstnotifdelay":
-						servicetemplate.firstnotifdelay = v.(string)
-					case "notifications_enabled":
-						servicetemplate.notifications_enabled = v.(string)
-					case "stalkingoptions":
-						servicetemplate.stalkingoptions = v.(string)
-					case "notes":
-						servicetemplate.notes = v.(string)
-					case "notes_url":
-						servicetemplate.notes_url = v.(string)
-					case "action_url":
-						servicetemplate.action_url, _ = UrlDecode(v.(string))
-					case "icon_image":
-						servicetemplate.icon_image = v.(string)
-					case "icon_image_alt":
-						servicetemplate.icon_image_alt = v.(string)
-					case "vrml_image":
-						servicetemplate.vrml_image = v.(string)
-					case "statusmap_image":
-						servicetemplate.statusmap_image = v.(string)
-					case "coords2d":
-						servicetemplate.coords2d = v.(string)
-					case "coords3d":
-						servicetemplate.coords3d = v.(string)
-					}
+	body, err := clientOrDefault(h.client).get(ctx, url, endpoint, folder,
+		"servicetemplates", data)
+	if err != nil {
+		return err
+	}
+
+	// [[{"name":"web1"},{"alias":"Web server"},...],...]
+	var reply [][]map[string]string
+	if err := json.Unmarshal(body, &reply); err != nil {
+		return DecodeError{body, err}
+	}
+
+	t, _ := Table("servicetemplates")
+
+	for _, j := range reply {
+		servicetemplate := ServiceTemplate{}
+		for _, content := range j {
+			for k, val := range content {
+				if t.IsEncoded(k) {
+					val, _ = UrlDecode(val)
 				}
+				servicetemplate.SetField(k, val)
 			}
-			h.servicetemplates = append(h.servicetemplates, servicetemplate)
 		}
-
-		return nil
+		h.servicetemplates = append(h.servicetemplates, servicetemplate)
 	}
+
+	return nil
 }
 
 /*
- * Send HTTP POST request
+ * Fetch the records of the client's folder that pass q's filter, then
+ * apply q's column selection and sort order. See Query for which part of
+ * the filter the server is asked to apply.
  */
-func (h Servicetemplates) Post(url, endpoint, folder string, data []string) (e error) {
+func (h *Servicetemplates) GetQuery(ctx context.Context, q Query) error {
+
+	t, _ := Table("servicetemplates")
 
-	for strings.HasSuffix(url, "/") {
-		url = strings.TrimSuffix(url, "/")
+	data, f, err := showData(t, q)
+	if err != nil {
+		return err
 	}
-	for strings.HasSuffix(endpoint, "/") {
-		url = strings.TrimSuffix(endpoint, "/")
+	if len(q.Columns) > 0 {
+		if err := checkColumns(h.Options(), "servicetemplates", q.Columns); err != nil {
+			return err
+		}
+	}
+	order := []sortKey(nil)
+	if len(q.Sort) > 0 {
+		if order, err = parseSort(h.Options(), "servicetemplates", q.Sort); err != nil {
+			return err
+		}
 	}
 
-	fullUrl := url + "/" + endpoint
+	got := Servicetemplates{client: h.client}
+	if err := got.GetContext(ctx, "", "rest/show/servicetemplates", "", data); err != nil {
+		return err
+	}
 
-	// Format data
-	dataStr := "json={\"folder\":\"" + folder + "\""
-	dataInr, err := FormatData(data, "servicetemplates")
-	if err != nil {
-		txt := fmt.Sprintf("Could not format data. Check the '-d' option.")
-		return HttpError{txt}
+	for _, k := range got.servicetemplates {
+		if f == nil || f.Match(k) {
+			h.servicetemplates = append(h.servicetemplates, k)
+		}
 	}
-	if dataInr != "" {
-		dataStr += "," + dataInr
+	if len(q.Columns) > 0 {
+		h.columns = append([]string{}, q.Columns...)
 	}
-	dataStr += "}"
+	if order != nil {
+		h.order = order
+	}
+
+	return nil
+}
+
+/*
+ * Send HTTP POST request
+ */
+func (h Servicetemplates) Post(url, endpoint, folder string, data []string) (e error) {
+	return h.PostContext(context.Background(), url, endpoint, folder, data)
+}
 
-	//fmt.Printf("URL=%s\n", fullUrl)
-	//fmt.Printf("Data=%s\n", dataStr)
+func (h Servicetemplates) PostContext(ctx context.Context, url, endpoint,
+	folder string, data []string) (e error) {
 
-	buf := bytes.NewBuffer([]byte(dataStr))
+	_, err := clientOrDefault(h.client).post(ctx, url, endpoint, folder,
+		"servicetemplates", data)
 
-	//fmt.Printf("json=%s\n", buf)
+	return err
+}
 
-	// accept bad certs
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-	}
-	client := &http.Client{Transport: tr}
+/*
+ * Add a record to the client's folder. The required fields are checked
+ * before anything is sent.
+ */
+func (h Servicetemplates) Add(ctx context.Context, r ServiceTemplate) error {
 
-	//resp, err := client.Post(fullUrl, "application/x-www-form-urlencoded", buf)
-	req, err := http.NewRequest("POST", fullUrl, buf)
-	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-	if len(h.username) > 0 {
-		req.SetBasicAuth(h.username, h.password)
-	}
-	resp, err := client.Do(req)
+	data, err := addData("servicetemplates", r)
 	if err != nil {
-		txt := fmt.Sprintf("Could not send REST request ('%s').", err.Error())
-		return HttpError{txt}
+		return err
 	}
 
-	defer resp.Body.Close()
+	return h.PostContext(ctx, "", "rest/add/servicetemplates", "", data)
+}
 
-	var body []byte
-	if b, err := ioutil.ReadAll(resp.Body); err != nil {
-		txt := fmt.Sprintf("Error reading Body ('%s').", err.Error())
-		return HttpError{txt}
-	} else {
-		body = b
+/*
+ * Modify the record identified by the key fields of key. Changes map
+ * field names to their new values.
+ */
+func (h Servicetemplates) Modify(ctx context.Context, key ServiceTemplate,
+	changes map[string]string) error {
+
+	data, err := modifyData("servicetemplates", key, changes)
+	if err != nil {
+		return err
 	}
 
-	if resp.StatusCode != 200 {
+	return h.PostContext(ctx, "", "rest/modify/servicetemplates", "", data)
+}
 
-		response, _ := UrlDecode(string(body))
-		txt := fmt.Sprintf("Status (%d): %s", resp.StatusCode, response)
-		return HttpError{txt}
+/*
+ * Delete the record identified by the key fields of key
+ */
+func (h Servicetemplates) Delete(ctx context.Context, key ServiceTemplate) error {
 
+	data, err := keyData("servicetemplates", key)
+	if err != nil {
+		return err
 	}
 
-	// If we get here then it was a success
-	return nil
+	return h.PostContext(ctx, "", "rest/delete/servicetemplates", "", data)
 }
@@ -0,0 +1,161 @@
+package nrc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// SnapshotVersion is the version of the snapshot document written by
+// this package. Documents with a later version are refused.
+const SnapshotVersion = 1
+
+// Snapshot holds every table of a folder. Each record is kept as a map
+// of its non-empty fields.
+type Snapshot struct {
+	Version int                            `json:"version"`
+	Folder  string                         `json:"folder"`
+	Taken   time.Time                      `json:"taken"`
+	Tables  map[string][]map[string]string `json:"tables"`
+}
+
+/*
+ * Fetch every table of the client's folder, all at once
+ */
+func TakeSnapshot(ctx context.Context, c *Client) (*Snapshot, error) {
+
+	c = clientOrDefault(c)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	s := &Snapshot{}
+	s.Version = SnapshotVersion
+	s.Folder = c.Folder
+	s.Taken = time.Now().UTC()
+	s.Tables = make(map[string][]map[string]string)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var firstErr error
+
+	for _, table := range TableNames() {
+		wg.Add(1)
+		go func(table string) {
+			defer wg.Done()
+
+			recs, err := fetchRecords(ctx, c, table)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s: %w", table, err)
+					cancel()
+				}
+				return
+			}
+			rows := []map[string]string{}
+			for _, r := range recs {
+				rows = append(rows, recordMap(r))
+			}
+			s.Tables[table] = rows
+		}(table)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return s, nil
+}
+
+/*
+ * Read a snapshot document written by WriteJSON
+ */
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+
+	s := &Snapshot{}
+	if err := json.NewDecoder(r).Decode(s); err != nil {
+		return nil, err
+	}
+	if s.Version < 1 || s.Version > SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", s.Version)
+	}
+	for table := range s.Tables {
+		if _, ok := Table(table); !ok {
+			return nil, fmt.Errorf("snapshot has unknown table '%s'", table)
+		}
+	}
+
+	return s, nil
+}
+
+/*
+ * Write the snapshot as an indented json document
+ */
+func (s *Snapshot) WriteJSON(w io.Writer) error {
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(s)
+}
+
+/*
+ * Return the records held for table. Records are pointers to the table's
+ * record type.
+ */
+func (s *Snapshot) Records(table string) ([]Record, error) {
+
+	t, ok := Table(table)
+	if !ok {
+		return nil, ValidationError{table, nil,
+			fmt.Sprintf("Unknown table '%s'.", table)}
+	}
+
+	out := []Record{}
+	for n, m := range s.Tables[table] {
+		r := newRecord(table)
+		for k, v := range m {
+			if !t.HasField(k) {
+				return nil, ValidationError{table, []string{k},
+					fmt.Sprintf("Unknown field '%s' in %s record %d.", k,
+						table, n+1)}
+			}
+			r.SetField(k, v)
+		}
+		out = append(out, r)
+	}
+
+	return out, nil
+}
+
+/*
+ * Add every record of the snapshot to the client's folder, which would
+ * normally be empty or a different folder from the one snapshotted.
+ * Tables are restored in dependency order: timeperiods and commands,
+ * contacts, templates, groups, hosts, services, dependencies,
+ * escalations and extinfo. The first failure stops the restore.
+ */
+func (s *Snapshot) Restore(ctx context.Context, c *Client) error {
+
+	for _, table := range dependencyOrder {
+		recs, err := s.Records(table)
+		if err != nil {
+			return err
+		}
+		for n, r := range recs {
+			if err := addRecord(ctx, c, r); err != nil {
+				return fmt.Errorf("%s record %d: %w", table, n+1, err)
+			}
+		}
+	}
+
+	return nil
+}
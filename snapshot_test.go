@@ -0,0 +1,190 @@
+package nrc_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	nrc "github.com/mclarkson/nagrestconf-golib"
+	"github.com/mclarkson/nagrestconf-golib/nrctest"
+)
+
+// The tables loaded into the folder snapshotted, in dependency order
+var snapshotTables = []string{"timeperiods", "commands", "hostgroups",
+	"hosts", "services", "hostdeps"}
+
+/*
+ * Start a server whose local folder has a few tables, some with values
+ * that need url-encoding
+ */
+func newSnapshotServer() *nrctest.Server {
+
+	s := nrctest.NewServer()
+	s.Load("local", "hostdeps",
+		map[string]string{"hostname": "web1", "dephostname": "db1"})
+	s.Load("local", "services",
+		map[string]string{"name": "web1", "template": "svctmpl-local",
+			"command": "check_http!-u /a%20b?x=1&y=2+3",
+			"svcdesc": "HTTP & HTTPS 100%"})
+	s.Load("local", "hosts",
+		map[string]string{"name": "web1", "alias": "web & app",
+			"ipaddress": "10.0.0.1", "template": "hsttmpl-local",
+			"hostgroup": "linux"},
+		map[string]string{"name": "db1", "alias": "db",
+			"ipaddress": "10.0.0.2", "template": "hsttmpl-local"})
+	s.Load("local", "hostgroups",
+		map[string]string{"name": "linux", "alias": "Linux"})
+	s.Load("local", "commands",
+		map[string]string{"name": "check_x+y", "command": "$USER1$/x -a '%s'"})
+	s.Load("local", "timeperiods",
+		map[string]string{"name": "24x7", "alias": "Always",
+			"definition": "monday:00:00-24:00"})
+
+	return s
+}
+
+/*
+ * Return a folder's table with empty fields left out
+ */
+func nonEmpty(s *nrctest.Server, folder, table string) []map[string]string {
+
+	out := []map[string]string{}
+	for _, r := range s.Records(folder, table) {
+		m := map[string]string{}
+		for k, v := range r {
+			if v != "" {
+				m[k] = v
+			}
+		}
+		out = append(out, m)
+	}
+
+	return out
+}
+
+func TestSnapshotRestore(t *testing.T) {
+
+	src := newSnapshotServer()
+	defer src.Close()
+	dst := nrctest.NewServer()
+	defer dst.Close()
+	ctx := context.Background()
+
+	snap, err := nrc.TakeSnapshot(ctx, src.NrcClient("local"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Version != nrc.SnapshotVersion || snap.Folder != "local" {
+		t.Errorf("snapshot version %d folder %q", snap.Version, snap.Folder)
+	}
+
+	var buf bytes.Buffer
+	if err := snap.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	read, err := nrc.ReadSnapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read.Tables, snap.Tables) {
+		t.Errorf("read back:\n%q\nwant:\n%q", read.Tables, snap.Tables)
+	}
+
+	if err := read.Restore(ctx, dst.NrcClient("local")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, table := range nrc.TableNames() {
+		got, want := nonEmpty(dst, "local", table),
+			nonEmpty(src, "local", table)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s restored as:\n%q\nwant:\n%q", table, got, want)
+		}
+	}
+
+	// Tables are added in dependency order
+	order := []string{}
+	for _, i := range dst.Requests() {
+		table := strings.TrimPrefix(i, "rest/add/")
+		if table == i {
+			t.Errorf("restore sent %s", i)
+			continue
+		}
+		if len(order) == 0 || order[len(order)-1] != table {
+			order = append(order, table)
+		}
+	}
+	if !reflect.DeepEqual(order, snapshotTables) {
+		t.Errorf("restored in order %q, want %q", order, snapshotTables)
+	}
+}
+
+func TestReadSnapshotRefused(t *testing.T) {
+
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"later version", `{"version": 2, "tables": {}}`},
+		{"no version", `{"tables": {}}`},
+		{"unknown table", `{"version": 1, "tables": {"printers": []}}`},
+		{"not json", `version: 1`},
+	}
+
+	for _, i := range tests {
+		if _, err := nrc.ReadSnapshot(strings.NewReader(i.doc)); err == nil {
+			t.Errorf("%s: snapshot was read", i.name)
+		}
+	}
+
+	s, err := nrc.ReadSnapshot(strings.NewReader(
+		`{"version": 1, "tables": {"hosts": [{"name": "web1"}]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recs, err := s.Records("hosts")
+	if err != nil || len(recs) != 1 {
+		t.Errorf("Records: got %v, %v", recs, err)
+	}
+}
+
+func TestTakeSnapshotCancels(t *testing.T) {
+
+	// hosts fails at once; every other table waits to be cancelled
+	var cancelled int32
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/show/hosts") {
+				w.WriteHeader(400)
+				w.Write([]byte(`["ERROR: no hosts."]`))
+				return
+			}
+			select {
+			case <-r.Context().Done():
+				atomic.AddInt32(&cancelled, 1)
+			case <-time.After(10 * time.Second):
+				w.Write([]byte("[]"))
+			}
+		}))
+
+	start := time.Now()
+	_, err := nrc.TakeSnapshot(context.Background(),
+		nrc.NewClient(srv.URL, "", "", "local"))
+	if err == nil || !strings.HasPrefix(err.Error(), "hosts: ") {
+		t.Errorf("got %v, want the hosts error", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("snapshot took %v, the other fetches were not cancelled", d)
+	}
+
+	srv.Close()
+	if atomic.LoadInt32(&cancelled) == 0 {
+		t.Error("no fetch was cancelled")
+	}
+}
@@ -0,0 +1,134 @@
+package nrc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// One field of a sort order
+type sortKey struct {
+	field string
+	desc  bool
+}
+
+/*
+ * Parse sort fields. A leading '-' sorts that field descending and a
+ * leading '+' ascending, the default. Every field must be one of
+ * options, the fields of table.
+ */
+func parseSort(options []string, table string,
+	fields []string) ([]sortKey, error) {
+
+	valid := make(map[string]bool)
+	for _, i := range options {
+		valid[i] = true
+	}
+
+	order := []sortKey{}
+	unknown := []string{}
+	for _, i := range fields {
+		k := sortKey{}
+		switch {
+		case strings.HasPrefix(i, "-"):
+			k.desc = true
+			k.field = i[1:]
+		case strings.HasPrefix(i, "+"):
+			k.field = i[1:]
+		default:
+			k.field = i
+		}
+		if !valid[k.field] {
+			unknown = append(unknown, k.field)
+			continue
+		}
+		order = append(order, k)
+	}
+	if len(unknown) > 0 {
+		txt := fmt.Sprintf("Unknown sort field(s) for %s: %s.", table,
+			strings.Join(unknown, ", "))
+		return nil, ValidationError{table, unknown, txt}
+	}
+
+	return order, nil
+}
+
+/*
+ * Compare two records field by field in the given order. Returns -1, 0
+ * or 1.
+ */
+func compareRecords(order []sortKey, a, b Record) int {
+
+	for _, k := range order {
+		x, _ := a.Field(k.field)
+		y, _ := b.Field(k.field)
+		c := naturalCompare(x, y)
+		if k.desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+/*
+ * Compare strings so that runs of digits are ordered by their numeric
+ * value, putting web9 before web10. Returns -1, 0 or 1.
+ */
+func naturalCompare(a, b string) int {
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+
+		if !isDigit(a[i]) || !isDigit(b[j]) {
+			if a[i] != b[j] {
+				if a[i] < b[j] {
+					return -1
+				}
+				return 1
+			}
+			i++
+			j++
+			continue
+		}
+
+		// Both at a run of digits
+		si, sj := i, j
+		for i < len(a) && isDigit(a[i]) {
+			i++
+		}
+		for j < len(b) && isDigit(b[j]) {
+			j++
+		}
+		x := strings.TrimLeft(a[si:i], "0")
+		y := strings.TrimLeft(b[sj:j], "0")
+		if len(x) != len(y) {
+			if len(x) < len(y) {
+				return -1
+			}
+			return 1
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case len(a)-i < len(b)-j:
+		return -1
+	case len(a)-i > len(b)-j:
+		return 1
+	}
+
+	// Equal apart from leading zeros, web01 and web1
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,124 @@
+package nrc
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNaturalCompare(t *testing.T) {
+
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"web9", "web10", -1},
+		{"web10", "web9", 1},
+		{"web10", "web10", 0},
+		{"web1a", "web1b", -1},
+		{"web2-eth10", "web2-eth9", 1},
+		{"web", "web1", -1},
+		{"", "a", -1},
+		{"10", "9", 1},
+		{"a10b2", "a10b10", -1},
+
+		// Leading zeros only break ties, so the order stays total
+		{"web01", "web1", -1},
+		{"web1", "web01", 1},
+		{"web001", "web2", -1},
+		{"web010", "web9", 1},
+		{"web01b", "web1a", 1},
+	}
+
+	for _, i := range tests {
+		if got := naturalCompare(i.a, i.b); got != i.want {
+			t.Errorf("naturalCompare(%q, %q) = %d, want %d", i.a, i.b, got,
+				i.want)
+		}
+	}
+}
+
+func TestParseSort(t *testing.T) {
+
+	options := []string{"name", "alias", "ipaddress"}
+
+	order, err := parseSort(options, "hosts",
+		[]string{"-alias", "+name", "ipaddress"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []sortKey{{"alias", true}, {"name", false}, {"ipaddress", false}}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("got %+v, want %+v", order, want)
+	}
+
+	_, err = parseSort(options, "hosts", []string{"name", "-colour", "+size"})
+	var ve ValidationError
+	if !errors.As(err, &ve) || !reflect.DeepEqual(ve.Fields,
+		[]string{"colour", "size"}) {
+		t.Errorf("unknown fields: got %v, want a ValidationError", err)
+	}
+}
+
+func TestSortBy(t *testing.T) {
+
+	h := Hosts{hosts: []Host{
+		{Name: "web10", Alias: "b"},
+		{Name: "db1", Alias: "a"},
+		{Name: "web9", Alias: "b"},
+		{Name: "web2", Alias: "a"},
+		{Name: "mail1", Alias: "b"},
+		{Name: "web01", Alias: "a"},
+		{Name: "web1", Alias: "a"},
+	}}
+
+	names := func() []string {
+		out := []string{}
+		for _, r := range h.Records() {
+			out = append(out, r.Name)
+		}
+		return out
+	}
+
+	tests := []struct {
+		fields []string
+		want   []string
+	}{
+		{[]string{"name"}, []string{"db1", "mail1", "web01", "web1", "web2",
+			"web9", "web10"}},
+		{[]string{"-name"}, []string{"web10", "web9", "web2", "web1",
+			"web01", "mail1", "db1"}},
+		{[]string{"-alias", "+name"}, []string{"mail1", "web9", "web10",
+			"db1", "web01", "web1", "web2"}},
+		{[]string{"alias", "-name"}, []string{"web2", "web1", "web01",
+			"db1", "web10", "web9", "mail1"}},
+
+		// Ties keep the order the server returned
+		{[]string{"alias"}, []string{"db1", "web2", "web01", "web1",
+			"web10", "web9", "mail1"}},
+		{[]string{"-alias"}, []string{"web10", "web9", "mail1", "db1",
+			"web2", "web01", "web1"}},
+		{nil, []string{"web10", "db1", "web9", "web2", "mail1", "web01",
+			"web1"}},
+	}
+
+	for _, i := range tests {
+		if err := h.SortBy(i.fields...); err != nil {
+			t.Fatalf("%q: %v", i.fields, err)
+		}
+		if got := names(); !reflect.DeepEqual(got, i.want) {
+			t.Errorf("%q: got %q, want %q", i.fields, got, i.want)
+		}
+	}
+
+	// A bad sort leaves the order as it was
+	if err := h.SortBy("name"); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.SortBy("colour"); err == nil {
+		t.Error("unknown sort field accepted")
+	}
+	if got := names(); got[0] != "db1" {
+		t.Errorf("after a bad SortBy got %q, want the name order kept", got)
+	}
+}
@@ -0,0 +1,77 @@
+package nrc
+
+//go:generate go run ./internal/gen
+
+// TableInfo describes one nagrestconf table: its fields in column order,
+// the fields an add request must supply, the fields the server returns
+// url-encoded and the fields that identify a record in modify and delete
+// requests. The registry itself is generated into tables.go from
+// internal/gen/schema.json.
+type TableInfo struct {
+	Name     string
+	Fields   []string
+	Required []string
+	Encoded  []string
+	Keys     []string
+}
+
+/*
+ * Look up a table by name, e.g. "hosts". The slices returned are copies.
+ */
+func Table(name string) (TableInfo, bool) {
+
+	t, ok := tableInfo[name]
+	if !ok {
+		return TableInfo{}, false
+	}
+
+	t.Fields = append([]string{}, t.Fields...)
+	t.Required = append([]string{}, t.Required...)
+	t.Encoded = append([]string{}, t.Encoded...)
+	t.Keys = append([]string{}, t.Keys...)
+
+	return t, true
+}
+
+/*
+ * Return the names of all tables in the order schema.json lists them
+ */
+func TableNames() []string {
+	return append([]string{}, tableNames...)
+}
+
+func (t TableInfo) HasField(field string) bool {
+	for _, i := range t.Fields {
+		if i == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (t TableInfo) IsEncoded(field string) bool {
+	for _, i := range t.Encoded {
+		if i == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (t TableInfo) IsKey(field string) bool {
+	for _, i := range t.Keys {
+		if i == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (t TableInfo) IsRequired(field string) bool {
+	for _, i := range t.Required {
+		if i == field {
+			return true
+		}
+	}
+	return false
+}
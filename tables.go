@@ -0,0 +1,174 @@
+// Code generated by internal/gen from schema.json; DO NOT EDIT.
+
+package nrc
+
+var tableNames = []string{"hosts", "services", "servicesets", "hosttemplates", "servicetemplates", "hostgroups", "servicegroups", "contacts", "contactgroups", "timeperiods", "commands", "servicedeps", "hostdeps", "serviceesc", "hostesc", "serviceextinfo", "hostextinfo"}
+
+// Tables in an order that lets each be added after the tables its
+// records refer to
+var dependencyOrder = []string{"timeperiods", "commands", "contacts", "contactgroups", "hosttemplates", "servicetemplates", "hostgroups", "servicegroups", "servicesets", "hosts", "services", "servicedeps", "hostdeps", "serviceesc", "hostesc", "serviceextinfo", "hostextinfo"}
+
+var tableInfo = map[string]TableInfo{
+	"hosts": {
+		Name:     "hosts",
+		Fields:   []string{"name", "alias", "ipaddress", "template", "hostgroup", "contact", "contactgroups", "activechecks", "servicesets", "disable", "displayname", "parents", "command", "initialstate", "maxcheckattempts", "checkinterval", "retryinterval", "passivechecks", "checkperiod", "obsessoverhost", "checkfreshness", "freshnessthresh", "eventhandler", "eventhandlerenabled", "lowflapthresh", "highflapthresh", "flapdetectionenabled", "flapdetectionoptions", "processperfdata", "retainstatusinfo", "retainnonstatusinfo", "notifinterval", "firstnotifdelay", "notifperiod", "notifopts", "notifications_enabled", "stalkingoptions", "notes", "notes_url", "icon_image", "icon_image_alt", "vrml_image", "statusmap_image", "coords2d", "coords3d", "action_url", "customvars"},
+		Required: []string{"name", "alias", "ipaddress", "template"},
+		Encoded:  []string{"command", "alias"},
+		Keys:     []string{"name"},
+	},
+	"services": {
+		Name:     "services",
+		Fields:   []string{"name", "template", "command", "svcdesc", "svcgroup", "contacts", "contactgroups", "freshnessthresh", "activechecks", "customvars", "disable", "displayname", "isvolatile", "initialstate", "maxcheckattempts", "checkinterval", "retryinterval", "passivechecks", "checkperiod", "obsessoverservice", "manfreshnessthresh", "checkfreshness", "eventhandler", "eventhandlerenabled", "lowflapthresh", "highflapthresh", "flapdetectionenabled", "flapdetectionoptions", "processperfdata", "retainstatusinfo", "retainnonstatusinfo", "notifinterval", "firstnotifdelay", "notifperiod", "notifopts", "notifications_enabled", "stalkingoptions", "notes", "notes_url", "action_url", "icon_image", "icon_image_alt", "vrml_image", "statusmap_image", "coords2d", "coords3d"},
+		Required: []string{"name", "template", "command", "svcdesc"},
+		Encoded:  []string{"name", "command", "svcdesc"},
+		Keys:     []string{"name", "svcdesc"},
+	},
+	"servicesets": {
+		Name:     "servicesets",
+		Fields:   []string{"name", "template", "command", "svcdesc", "svcgroup", "contacts", "contactgroups", "freshnessthresh", "activechecks", "customvars", "disable", "displayname", "isvolatile", "initialstate", "maxcheckattempts", "checkinterval", "retryinterval", "passivechecks", "checkperiod", "obsessoverservice", "manfreshnessthresh", "checkfreshness", "eventhandler", "eventhandlerenabled", "lowflapthresh", "highflapthresh", "flapdetectionenabled", "flapdetectionoptions", "processperfdata", "retainstatusinfo", "retainnonstatusinfo", "notifinterval", "firstnotifdelay", "notifperiod", "notifopts", "notifications_enabled", "stalkingoptions", "notes", "notes_url", "action_url", "icon_image", "icon_image_alt", "vrml_image", "statusmap_image", "coords2d", "coords3d"},
+		Required: []string{"name", "template", "command", "svcdesc"},
+		Encoded:  []string{"name", "command", "svcdesc"},
+		Keys:     []string{"name", "svcdesc"},
+	},
+	"hosttemplates": {
+		Name:     "hosttemplates",
+		Fields:   []string{"name", "use", "contacts", "contactgroups", "normchecki", "checkinterval", "retryinterval", "notifperiod", "notifopts", "disable", "checkperiod", "maxcheckattempts", "checkcommand", "notifinterval", "passivechecks", "obsessoverhost", "checkfreshness", "freshnessthresh", "eventhandler", "eventhandlerenabled", "lowflapthresh", "highflapthresh", "flapdetectionenabled", "flapdetectionoptions", "processperfdata", "retainstatusinfo", "retainnonstatusinfo", "firstnotifdelay", "notifications_enabled", "stalkingoptions", "notes", "notes_url", "icon_image", "icon_image_alt", "vrml_image", "statusmap_image", "coords2d", "coords3d", "action_url", "customvars"},
+		Required: []string{"name", "checkinterval", "retryinterval", "notifperiod", "checkperiod", "maxcheckattempts", "notifinterval"},
+		Encoded:  []string{"checkcommand", "action_url"},
+		Keys:     []string{"name"},
+	},
+	"servicetemplates": {
+		Name:     "servicetemplates",
+		Fields:   []string{"name", "use", "contacts", "contactgroups", "notifopts", "checkinterval", "normchecki", "retryinterval", "notifinterval", "notifperiod", "disable", "checkperiod", "maxcheckattempts", "freshnessthresh", "activechecks", "customvars", "isvolatile", "initialstate", "passivechecks", "obsessoverservice", "manfreshnessthresh", "checkfreshness", "eventhandler", "eventhandlerenabled", "lowflapthresh", "highflapthresh", "flapdetectionenabled", "flapdetectionoptions", "processperfdata", "retainstatusinfo", "retainnonstatusinfo", "firstnotifdelay", "notifications_enabled", "stalkingoptions", "notes", "notes_url", "action_url", "icon_image", "icon_image_alt", "vrml_image", "statusmap_image", "coords2d", "coords3d"},
+		Required: []string{"name", "checkinterval", "retryinterval", "notifinterval", "notifperiod", "checkperiod", "maxcheckattempts"},
+		Encoded:  []string{"action_url"},
+		Keys:     []string{"name"},
+	},
+	"hostgroups": {
+		Name:     "hostgroups",
+		Fields:   []string{"name", "alias", "disable", "members", "hostgroupmembers", "notes", "notes_url", "action_url"},
+		Required: []string{"name", "alias"},
+		Encoded:  []string{},
+		Keys:     []string{"name"},
+	},
+	"servicegroups": {
+		Name:     "servicegroups",
+		Fields:   []string{"name", "alias", "disable", "members", "servicegroupmembers", "notes", "notes_url", "action_url"},
+		Required: []string{"name", "alias"},
+		Encoded:  []string{},
+		Keys:     []string{"name"},
+	},
+	"contacts": {
+		Name:     "contacts",
+		Fields:   []string{"name", "use", "alias", "emailaddr", "svcnotifperiod", "svcnotifopts", "svcnotifcmds", "hstnotifperiod", "hstnotifopts", "hstnotifcmds", "cansubmitcmds", "disable", "svcnotifenabled", "hstnotifenabled", "pager", "address1", "address2", "address3", "address4", "address5", "address6", "retainstatusinfo", "retainnonstatusinfo", "contactgroups"},
+		Required: []string{"name", "alias", "svcnotifperiod", "svcnotifopts", "svcnotifcmds", "hstnotifperiod", "hstnotifopts", "hstnotifcmds"},
+		Encoded:  []string{},
+		Keys:     []string{"name"},
+	},
+	"contactgroups": {
+		Name:     "contactgroups",
+		Fields:   []string{"name", "alias", "members", "disable"},
+		Required: []string{"name", "alias", "members"},
+		Encoded:  []string{},
+		Keys:     []string{"name"},
+	},
+	"timeperiods": {
+		Name:     "timeperiods",
+		Fields:   []string{"name", "alias", "definition", "exclude", "disable", "exception"},
+		Required: []string{"name", "alias"},
+		Encoded:  []string{},
+		Keys:     []string{"name"},
+	},
+	"commands": {
+		Name:     "commands",
+		Fields:   []string{"name", "command", "disable"},
+		Required: []string{"name", "command"},
+		Encoded:  []string{"name", "command"},
+		Keys:     []string{"name"},
+	},
+	"servicedeps": {
+		Name:     "servicedeps",
+		Fields:   []string{"dephostname", "dephostgroupname", "depsvcdesc", "hostname", "hostgroupname", "svcdesc", "inheritsparent", "execfailcriteria", "notiffailcriteria", "period", "disable"},
+		Required: []string{},
+		Encoded:  []string{},
+		Keys:     []string{"dephostname", "dephostgroupname", "depsvcdesc", "hostname", "hostgroupname", "svcdesc"},
+	},
+	"hostdeps": {
+		Name:     "hostdeps",
+		Fields:   []string{"dephostname", "dephostgroupname", "hostname", "hostgroupname", "inheritsparent", "execfailcriteria", "notiffailcriteria", "period", "disable"},
+		Required: []string{},
+		Encoded:  []string{},
+		Keys:     []string{"dephostname", "dephostgroupname", "hostname", "hostgroupname"},
+	},
+	"serviceesc": {
+		Name:     "serviceesc",
+		Fields:   []string{"hostname", "hostgroupname", "svcdesc", "contacts", "contactgroups", "firstnotif", "lastnotif", "notifinterval", "period", "escopts", "disable"},
+		Required: []string{},
+		Encoded:  []string{},
+		Keys:     []string{"hostname", "hostgroupname", "svcdesc"},
+	},
+	"hostesc": {
+		Name:     "hostesc",
+		Fields:   []string{"hostname", "hostgroupname", "contacts", "contactgroups", "firstnotif", "lastnotif", "notifinterval", "period", "escopts", "disable"},
+		Required: []string{},
+		Encoded:  []string{},
+		Keys:     []string{"hostname", "hostgroupname"},
+	},
+	"serviceextinfo": {
+		Name:     "serviceextinfo",
+		Fields:   []string{"hostname", "svcdesc", "notes", "notes_url", "action_url", "icon_image", "icon_image_alt", "disable"},
+		Required: []string{},
+		Encoded:  []string{},
+		Keys:     []string{"hostname", "svcdesc"},
+	},
+	"hostextinfo": {
+		Name:     "hostextinfo",
+		Fields:   []string{"hostname", "notes", "notes_url", "action_url", "icon_image", "icon_image_alt", "vrml_image", "statusmap_image", "coords2d", "coords3d", "disable"},
+		Required: []string{},
+		Encoded:  []string{},
+		Keys:     []string{"hostname"},
+	},
+}
+
+/*
+ * Return an empty record of the named table, or nil
+ */
+func newRecord(table string) FieldSetter {
+	switch table {
+	case "hosts":
+		return &Host{}
+	case "services":
+		return &Service{}
+	case "servicesets":
+		return &ServiceSet{}
+	case "hosttemplates":
+		return &HostTemplate{}
+	case "servicetemplates":
+		return &ServiceTemplate{}
+	case "hostgroups":
+		return &HostGroup{}
+	case "servicegroups":
+		return &ServiceGroup{}
+	case "contacts":
+		return &Contact{}
+	case "contactgroups":
+		return &ContactGroup{}
+	case "timeperiods":
+		return &Timeperiod{}
+	case "commands":
+		return &Command{}
+	case "servicedeps":
+		return &ServiceDependency{}
+	case "hostdeps":
+		return &HostDependency{}
+	case "serviceesc":
+		return &ServiceEscalation{}
+	case "hostesc":
+		return &HostEscalation{}
+	case "serviceextinfo":
+		return &ServiceExtInfo{}
+	case "hostextinfo":
+		return &HostExtInfo{}
+	}
+	return nil
+}
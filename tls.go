@@ -0,0 +1,127 @@
+package nrc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	hexenc "encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// TLSOptions describes how a Client verifies the nagrestconf server and
+// how it identifies itself. The zero value verifies the server against
+// the system roots.
+type TLSOptions struct {
+	InsecureSkipVerify bool   // accept any server certificate
+	CAFile             string // PEM bundle used instead of the system roots
+	Fingerprint        string // SHA-256 of the server certificate, hex
+	CertFile           string // client certificate PEM for mutual TLS
+	KeyFile            string // client key PEM for mutual TLS
+}
+
+/*
+ * Build a tls.Config from opts. Files that cannot be read and malformed
+ * fingerprints are reported as a ValidationError naming the option.
+ *
+ * When a Fingerprint is given without a CAFile the certificate chain is
+ * not verified and the pin alone decides, which suits the self-signed
+ * certificates nagrestconf is usually installed with. With a CAFile both
+ * the chain and the pin must match. A server that does not match fails
+ * with a FingerprintError inside the request's TransportError.
+ */
+func NewTLSConfig(opts TLSOptions) (*tls.Config, error) {
+
+	cfg := &tls.Config{InsecureSkipVerify: opts.InsecureSkipVerify}
+
+	if opts.CAFile != "" {
+		pem, err := ioutil.ReadFile(opts.CAFile)
+		if err != nil {
+			txt := fmt.Sprintf("Could not read CA file ('%s').", err.Error())
+			return nil, ValidationError{Fields: []string{"CAFile"},
+				Message: txt}
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			txt := fmt.Sprintf("No certificates found in CA file '%s'.",
+				opts.CAFile)
+			return nil, ValidationError{Fields: []string{"CAFile"},
+				Message: txt}
+		}
+		cfg.RootCAs = pool
+	}
+
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			txt := fmt.Sprintf("Could not load client certificate ('%s').",
+				err.Error())
+			return nil, ValidationError{Fields: []string{"CertFile",
+				"KeyFile"}, Message: txt}
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if opts.Fingerprint != "" {
+		pin, err := parseFingerprint(opts.Fingerprint)
+		if err != nil {
+			return nil, err
+		}
+		if opts.CAFile == "" {
+			cfg.InsecureSkipVerify = true
+		}
+		cfg.VerifyPeerCertificate = func(rawCerts [][]byte,
+			_ [][]*x509.Certificate) error {
+
+			if len(rawCerts) == 0 {
+				return FingerprintError{}
+			}
+			sum := sha256.Sum256(rawCerts[0])
+			if !bytes.Equal(sum[:], pin) {
+				return FingerprintError{hexenc.EncodeToString(sum[:])}
+			}
+			return nil
+		}
+	}
+
+	return cfg, nil
+}
+
+/*
+ * Accept fingerprints as plain hex or colon separated pairs, as printed
+ * by 'openssl x509 -fingerprint -sha256'.
+ */
+func parseFingerprint(s string) ([]byte, error) {
+
+	s = strings.Replace(s, ":", "", -1)
+	pin, err := hexenc.DecodeString(s)
+	if err != nil || len(pin) != sha256.Size {
+		txt := fmt.Sprintf("Invalid SHA-256 fingerprint '%s'.", s)
+		return nil, ValidationError{Fields: []string{"Fingerprint"},
+			Message: txt}
+	}
+
+	return pin, nil
+}
+
+/*
+ * Give the client its own transport configured from opts.
+ */
+func (c *Client) SetTLS(opts TLSOptions) error {
+
+	cfg, err := NewTLSConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	tr := &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: cfg,
+	}
+	c.HTTPClient = &http.Client{Transport: tr}
+
+	return nil
+}
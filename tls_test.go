@@ -0,0 +1,252 @@
+package nrc_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	nrc "github.com/mclarkson/nagrestconf-golib"
+)
+
+/*
+ * Start a TLS server, with httptest's self-signed certificate, that
+ * answers every show with an empty table and records the subject of
+ * any client certificate
+ */
+func newTLSServer(t *testing.T, clientAuth tls.ClientAuthType,
+	subject *string) *httptest.Server {
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if subject != nil && len(r.TLS.PeerCertificates) > 0 {
+				*subject = r.TLS.PeerCertificates[0].Subject.CommonName
+			}
+			w.Write([]byte("[]"))
+		}))
+	srv.TLS = &tls.Config{ClientAuth: clientAuth}
+	// Failed handshakes are what several tests expect
+	srv.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+/*
+ * Write the server's certificate as a PEM CA bundle
+ */
+func writeServerCA(t *testing.T, srv *httptest.Server) string {
+	return writeFile(t, "ca.pem", pem.EncodeToMemory(&pem.Block{
+		Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}))
+}
+
+func fingerprint(srv *httptest.Server) string {
+	sum := sha256.Sum256(srv.Certificate().Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+/*
+ * Send a show request through a client configured with opts
+ */
+func showWith(t *testing.T, srv *httptest.Server, opts nrc.TLSOptions) error {
+
+	c := nrc.NewClient(srv.URL, "", "", "local")
+	if err := c.SetTLS(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	return c.Hosts().GetContext(context.Background(), "", "rest/show/hosts",
+		"", nil)
+}
+
+func TestTLSDefaultRejectsSelfSigned(t *testing.T) {
+
+	srv := newTLSServer(t, tls.NoClientCert, nil)
+
+	if err := showWith(t, srv, nrc.TLSOptions{}); err == nil {
+		t.Error("self-signed certificate accepted without a CA file")
+	}
+}
+
+func TestTLSInsecureSkipVerify(t *testing.T) {
+
+	srv := newTLSServer(t, tls.NoClientCert, nil)
+
+	err := showWith(t, srv, nrc.TLSOptions{InsecureSkipVerify: true})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTLSCAFile(t *testing.T) {
+
+	srv := newTLSServer(t, tls.NoClientCert, nil)
+
+	err := showWith(t, srv, nrc.TLSOptions{CAFile: writeServerCA(t, srv)})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTLSBadCAFile(t *testing.T) {
+
+	_, err := nrc.NewTLSConfig(nrc.TLSOptions{
+		CAFile: writeFile(t, "ca.pem", []byte("not a certificate"))})
+	var ve nrc.ValidationError
+	if !errors.As(err, &ve) || ve.Fields[0] != "CAFile" {
+		t.Errorf("CA file without certificates: got %v, want a "+
+			"ValidationError", err)
+	}
+
+	_, err = nrc.NewTLSConfig(nrc.TLSOptions{
+		CAFile: filepath.Join(t.TempDir(), "missing.pem")})
+	if !errors.As(err, &ve) {
+		t.Errorf("missing CA file: got %v, want a ValidationError", err)
+	}
+}
+
+func TestTLSFingerprint(t *testing.T) {
+
+	srv := newTLSServer(t, tls.NoClientCert, nil)
+	pin := fingerprint(srv)
+
+	if err := showWith(t, srv, nrc.TLSOptions{Fingerprint: pin}); err != nil {
+		t.Errorf("right fingerprint: %v", err)
+	}
+
+	// As printed by openssl, upper case with colons
+	pairs := []string{}
+	for i := 0; i < len(pin); i += 2 {
+		pairs = append(pairs, strings.ToUpper(pin[i:i+2]))
+	}
+	err := showWith(t, srv,
+		nrc.TLSOptions{Fingerprint: strings.Join(pairs, ":")})
+	if err != nil {
+		t.Errorf("right fingerprint with colons: %v", err)
+	}
+
+	// Both the chain and the pin are checked when there is a CA file
+	err = showWith(t, srv, nrc.TLSOptions{Fingerprint: pin,
+		CAFile: writeServerCA(t, srv)})
+	if err != nil {
+		t.Errorf("right fingerprint and CA file: %v", err)
+	}
+
+	wrong := sha256.Sum256([]byte("some other certificate"))
+	err = showWith(t, srv,
+		nrc.TLSOptions{Fingerprint: hex.EncodeToString(wrong[:])})
+	var te nrc.TransportError
+	var fe nrc.FingerprintError
+	if !errors.As(err, &te) || !errors.As(err, &fe) {
+		t.Errorf("wrong fingerprint: got %v, want a FingerprintError in a "+
+			"TransportError", err)
+	} else if fe.Fingerprint != pin {
+		t.Errorf("error gives fingerprint %s, want the server's %s",
+			fe.Fingerprint, pin)
+	}
+}
+
+func TestTLSBadFingerprint(t *testing.T) {
+
+	for _, s := range []string{"zz", "abcd", strings.Repeat("0", 66)} {
+		_, err := nrc.NewTLSConfig(nrc.TLSOptions{Fingerprint: s})
+		var ve nrc.ValidationError
+		if !errors.As(err, &ve) || ve.Fields[0] != "Fingerprint" {
+			t.Errorf("fingerprint %q: got %v, want a ValidationError", s,
+				err)
+		}
+	}
+}
+
+/*
+ * Write a self-signed client certificate and its key, returning the
+ * paths of both
+ */
+func writeClientCert(t *testing.T, name string) (string, string) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey,
+		key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return writeFile(t, "cert.pem", pem.EncodeToMemory(&pem.Block{
+			Type: "CERTIFICATE", Bytes: der})),
+		writeFile(t, "key.pem", pem.EncodeToMemory(&pem.Block{
+			Type: "EC PRIVATE KEY", Bytes: keyDer}))
+}
+
+func TestTLSClientCertificate(t *testing.T) {
+
+	subject := ""
+	srv := newTLSServer(t, tls.RequireAnyClientCert, &subject)
+	cert, key := writeClientCert(t, "nrc-test-client")
+
+	if err := showWith(t, srv, nrc.TLSOptions{
+		CAFile: writeServerCA(t, srv)}); err == nil {
+		t.Error("server requiring a client certificate accepted none")
+	}
+
+	err := showWith(t, srv, nrc.TLSOptions{CAFile: writeServerCA(t, srv),
+		CertFile: cert, KeyFile: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject != "nrc-test-client" {
+		t.Errorf("server saw client certificate %q, want nrc-test-client",
+			subject)
+	}
+}
+
+func TestTLSMissingKeyFile(t *testing.T) {
+
+	cert, _ := writeClientCert(t, "nrc-test-client")
+
+	_, err := nrc.NewTLSConfig(nrc.TLSOptions{CertFile: cert})
+	var ve nrc.ValidationError
+	if !errors.As(err, &ve) {
+		t.Errorf("client certificate without a key: got %v, want a "+
+			"ValidationError", err)
+	}
+}
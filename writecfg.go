@@ -0,0 +1,448 @@
+package nrc
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/*
+ * Fetch every table of the client's folder into a NagiosConfig, ready
+ * for WriteDir. Servicesets have no Nagios equivalent so each host's
+ * servicesets are added to Services as services of that host.
+ */
+func FetchNagiosConfig(ctx context.Context, c *Client) (*NagiosConfig, error) {
+
+	cfg := &NagiosConfig{}
+
+	hosts := c.Hosts()
+	services := c.Services()
+	servicesets := c.Servicesets()
+	hosttemplates := c.Hosttemplates()
+	servicetemplates := c.Servicetemplates()
+	hostgroups := c.Hostgroups()
+	servicegroups := c.Servicegroups()
+	contacts := c.Contacts()
+	contactgroups := c.Contactgroups()
+	timeperiods := c.Timeperiods()
+	commands := c.Commands()
+	servicedeps := c.Servicedeps()
+	hostdeps := c.Hostdeps()
+	serviceesc := c.Serviceesc()
+	hostesc := c.Hostesc()
+	serviceextinfo := c.Serviceextinfo()
+	hostextinfo := c.Hostextinfo()
+
+	gets := []struct {
+		table string
+		q     NrcQueryContext
+	}{
+		{"hosts", hosts}, {"services", services},
+		{"servicesets", servicesets}, {"hosttemplates", hosttemplates},
+		{"servicetemplates", servicetemplates}, {"hostgroups", hostgroups},
+		{"servicegroups", servicegroups}, {"contacts", contacts},
+		{"contactgroups", contactgroups}, {"timeperiods", timeperiods},
+		{"commands", commands}, {"servicedeps", servicedeps},
+		{"hostdeps", hostdeps}, {"serviceesc", serviceesc},
+		{"hostesc", hostesc}, {"serviceextinfo", serviceextinfo},
+		{"hostextinfo", hostextinfo},
+	}
+	for _, i := range gets {
+		if err := i.q.GetContext(ctx, "", "rest/show/"+i.table, "",
+			nil); err != nil {
+			return nil, err
+		}
+	}
+
+	cfg.Hosts = hosts.Records()
+	cfg.Services = services.Records()
+	cfg.Hosttemplates = hosttemplates.Records()
+	cfg.Servicetemplates = servicetemplates.Records()
+	cfg.Hostgroups = hostgroups.Records()
+	cfg.Servicegroups = servicegroups.Records()
+	cfg.Contacts = contacts.Records()
+	cfg.Contactgroups = contactgroups.Records()
+	cfg.Timeperiods = timeperiods.Records()
+	cfg.Commands = commands.Records()
+	cfg.Servicedeps = servicedeps.Records()
+	cfg.Hostdeps = hostdeps.Records()
+	cfg.Serviceesc = serviceesc.Records()
+	cfg.Hostesc = hostesc.Records()
+	cfg.Serviceextinfo = serviceextinfo.Records()
+	cfg.Hostextinfo = hostextinfo.Records()
+
+	sets := servicesets.Records()
+	for _, h := range cfg.Hosts {
+		for _, name := range strings.Fields(h.Servicesets) {
+			for _, s := range sets {
+				if s.Name != name {
+					continue
+				}
+				svc := Service{}
+				for _, f := range tableInfo["servicesets"].Fields {
+					v, _ := s.Field(f)
+					svc.SetField(f, v)
+				}
+				svc.Name = h.Name
+				cfg.Services = append(cfg.Services, svc)
+			}
+		}
+	}
+
+	return cfg, nil
+}
+
+/*
+ * Fetch the client's folder and write it to dir as Nagios configuration
+ */
+func ExportNagios(ctx context.Context, c *Client, dir string) error {
+
+	cfg, err := FetchNagiosConfig(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	return cfg.WriteDir(dir)
+}
+
+/*
+ * Return the records held for table
+ */
+func (c *NagiosConfig) records(table string) []Record {
+
+	out := []Record{}
+	add := func(r Record) { out = append(out, r) }
+
+	switch table {
+	case "hosts":
+		for _, r := range c.Hosts {
+			add(r)
+		}
+	case "services":
+		for _, r := range c.Services {
+			add(r)
+		}
+	case "hosttemplates":
+		for _, r := range c.Hosttemplates {
+			add(r)
+		}
+	case "servicetemplates":
+		for _, r := range c.Servicetemplates {
+			add(r)
+		}
+	case "hostgroups":
+		for _, r := range c.Hostgroups {
+			add(r)
+		}
+	case "servicegroups":
+		for _, r := range c.Servicegroups {
+			add(r)
+		}
+	case "contacts":
+		for _, r := range c.Contacts {
+			add(r)
+		}
+	case "contactgroups":
+		for _, r := range c.Contactgroups {
+			add(r)
+		}
+	case "timeperiods":
+		for _, r := range c.Timeperiods {
+			add(r)
+		}
+	case "commands":
+		for _, r := range c.Commands {
+			add(r)
+		}
+	case "servicedeps":
+		for _, r := range c.Servicedeps {
+			add(r)
+		}
+	case "hostdeps":
+		for _, r := range c.Hostdeps {
+			add(r)
+		}
+	case "serviceesc":
+		for _, r := range c.Serviceesc {
+			add(r)
+		}
+	case "hostesc":
+		for _, r := range c.Hostesc {
+			add(r)
+		}
+	case "serviceextinfo":
+		for _, r := range c.Serviceextinfo {
+			add(r)
+		}
+	case "hostextinfo":
+		for _, r := range c.Hostextinfo {
+			add(r)
+		}
+	}
+
+	return out
+}
+
+// A field naming hosts. Alt is the hostgroup field that can stand in
+// for it, and keep means the record stays when it is emptied.
+type cfgHostRef struct {
+	field, alt string
+	keep       bool
+}
+
+// The fields of each table that name hosts
+var cfgHostRefs = map[string][]cfgHostRef{
+	"hosts":    {{"parents", "", true}},
+	"services": {{"name", "", false}},
+	"servicedeps": {{"hostname", "hostgroupname", false},
+		{"dephostname", "dephostgroupname", false}},
+	"hostdeps": {{"hostname", "hostgroupname", false},
+		{"dephostname", "dephostgroupname", false}},
+	"serviceesc":     {{"hostname", "hostgroupname", false}},
+	"hostesc":        {{"hostname", "hostgroupname", false}},
+	"serviceextinfo": {{"hostname", "", false}},
+	"hostextinfo":    {{"hostname", "", false}},
+	"hostgroups":     {{"members", "", true}},
+}
+
+/*
+ * Return a copy of the config without disabled hosts, as nagrestconf
+ * writes it. Disabled hosts are removed from every list naming them,
+ * and records left naming no host, such as the host's services, are
+ * dropped, since Nagios refuses objects that refer to undefined hosts.
+ */
+func (c *NagiosConfig) withoutDisabledHosts() *NagiosConfig {
+
+	disabled := make(map[string]bool)
+	for _, h := range c.Hosts {
+		if h.Disable == "1" {
+			disabled[h.Name] = true
+		}
+	}
+
+	out := &NagiosConfig{Unmapped: c.Unmapped}
+
+	for _, table := range TableNames() {
+	records:
+		for _, r := range c.records(table) {
+			m := recordMap(r)
+			if table == "hosts" && disabled[m["name"]] {
+				continue
+			}
+			for _, ref := range cfgHostRefs[table] {
+				if m[ref.field] == "" {
+					continue
+				}
+				hosts := []string{}
+				for _, i := range strings.Fields(m[ref.field]) {
+					if !disabled[i] {
+						hosts = append(hosts, i)
+					}
+				}
+				m[ref.field] = strings.Join(hosts, " ")
+				if m[ref.field] == "" && !ref.keep && m[ref.alt] == "" {
+					continue records
+				}
+			}
+			out.add(table, m)
+		}
+	}
+
+	return out
+}
+
+/*
+ * Write one file per table to dir, hosts.cfg, services.cfg and so on.
+ * Tables with no records get no file, and any file left by an earlier
+ * export is removed so Nagios does not load objects that are gone.
+ * Disabled hosts are left out along with the objects that refer to
+ * them alone.
+ */
+func (c *NagiosConfig) WriteDir(dir string) error {
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	c = c.withoutDisabledHosts()
+
+	for _, table := range TableNames() {
+		recs := c.records(table)
+		path := filepath.Join(dir, table+".cfg")
+		if len(recs) == 0 {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		err = WriteNagiosObjects(f, recs)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %s", path, err.Error())
+		}
+	}
+
+	return nil
+}
+
+/*
+ * Return the object type whose table, or template table, is table
+ */
+func cfgKindOf(table string) (string, cfgKind, bool) {
+
+	for name, k := range cfgKinds {
+		if k.table == table || k.templates == table {
+			return name, k, true
+		}
+	}
+
+	return "", cfgKind{}, false
+}
+
+/*
+ * Return the directive a field of table is written as
+ */
+func (k cfgKind) directive(t TableInfo, field string) (string, bool) {
+
+	for _, i := range k.fields {
+		if f, ok := k.field(t, i.directive); ok && f == field {
+			return i.directive, true
+		}
+	}
+
+	return "", false
+}
+
+/*
+ * Write records as Nagios define blocks. Host and service templates get
+ * 'register 0', records with disable set to 1 are left out, as
+ * nagrestconf leaves them out, and fields with no Nagios directive are
+ * written as comments.
+ */
+func WriteNagiosObjects(w io.Writer, records []Record) error {
+
+	bw := bufio.NewWriter(w)
+
+	for _, r := range records {
+		if err := writeNagiosObject(bw, r); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// One line of a define block
+type cfgLine struct {
+	directive, value string
+}
+
+func writeNagiosObject(w io.Writer, r Record) error {
+
+	table := r.TableName()
+	kind, k, ok := cfgKindOf(table)
+	if !ok {
+		return fmt.Errorf("no Nagios object type for %s", table)
+	}
+	t := tableInfo[table]
+	template := table == k.templates
+
+	if v, _ := r.Field("disable"); v == "1" {
+		return nil
+	}
+
+	lines := []cfgLine{}
+	comments := []cfgLine{}
+
+	for _, f := range t.Fields {
+		v, _ := r.Field(f)
+		if v == "" {
+			continue
+		}
+
+		switch {
+		case f == "disable" || f == "servicesets":
+			continue
+		case template && f == "name":
+			lines = append(lines, cfgLine{"name", v})
+			continue
+		case f == "template" || f == "use":
+			lines = append(lines, cfgLine{"use",
+				strings.Join(strings.Fields(v), ",")})
+			continue
+		case f == k.hosts && !template:
+			lines = append(lines, cfgLine{"host_name", cfgEscape(v)})
+			continue
+		case f == "customvars":
+			for _, i := range strings.Split(v, ";") {
+				if i = strings.TrimSpace(i); i == "" {
+					continue
+				}
+				name, val := i, ""
+				if n := strings.IndexAny(i, " \t"); n >= 0 {
+					name, val = i[:n], strings.TrimSpace(i[n+1:])
+				}
+				lines = append(lines, cfgLine{name, cfgEscape(val)})
+			}
+			continue
+		case f == "definition" || f == "exception":
+			for _, i := range strings.Split(v, ";") {
+				if i = strings.TrimSpace(i); i == "" {
+					continue
+				}
+				d, val := cfgSplitDirective(kind, i)
+				lines = append(lines, cfgLine{d, val})
+			}
+			continue
+		}
+
+		d, ok := k.directive(t, f)
+		if !ok {
+			comments = append(comments, cfgLine{f, v})
+			continue
+		}
+		if cfgListFields[f] {
+			v = strings.Join(strings.Fields(v), ",")
+		}
+		lines = append(lines, cfgLine{d, cfgEscape(v)})
+	}
+	if template {
+		lines = append(lines, cfgLine{"register", "0"})
+	}
+
+	width := 0
+	for _, i := range lines {
+		if len(i.directive) > width {
+			width = len(i.directive)
+		}
+	}
+
+	fmt.Fprintf(w, "define %s {\n", kind)
+	for _, i := range lines {
+		fmt.Fprintf(w, "    %-*s  %s\n", width, i.directive, i.value)
+	}
+	for _, i := range comments {
+		fmt.Fprintf(w, "    # nagrestconf %s: %s\n", i.directive,
+			strings.Replace(i.value, "\n", " ", -1))
+	}
+	_, err := fmt.Fprintf(w, "}\n\n")
+
+	return err
+}
+
+/*
+ * Escape ';', which would otherwise start a comment
+ */
+func cfgEscape(s string) string {
+	return strings.Replace(s, ";", "\\;", -1)
+}
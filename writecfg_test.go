@@ -0,0 +1,183 @@
+package nrc_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	nrc "github.com/mclarkson/nagrestconf-golib"
+	"github.com/mclarkson/nagrestconf-golib/nrctest"
+)
+
+func TestWriteDirRemovesEmptyTables(t *testing.T) {
+
+	dir := t.TempDir()
+
+	cfg := &nrc.NagiosConfig{
+		Hosts: []nrc.Host{{Name: "web1", Alias: "Web",
+			Ipaddress: "10.0.0.1"}},
+		Services: []nrc.Service{{Name: "web1", Command: "check_ping",
+			Svcdesc: "PING"}},
+	}
+	if err := cfg.WriteDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []string{"hosts.cfg", "services.cfg"} {
+		if _, err := os.Stat(filepath.Join(dir, i)); err != nil {
+			t.Errorf("%s not written: %v", i, err)
+		}
+	}
+
+	// A file that is not one of ours must survive
+	other := filepath.Join(dir, "local.cfg")
+	if err := ioutil.WriteFile(other, []byte("# mine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Services = nil
+	if err := cfg.WriteDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	_, err := os.Stat(filepath.Join(dir, "services.cfg"))
+	if !os.IsNotExist(err) {
+		t.Errorf("services.cfg left behind after its table emptied (%v)", err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("local.cfg removed: %v", err)
+	}
+
+	got, err := nrc.ParseNagiosConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Services) != 0 {
+		t.Errorf("read back %d services, want 0", len(got.Services))
+	}
+	if len(got.Hosts) != 1 || got.Hosts[0].Name != "web1" {
+		t.Errorf("read back hosts %+v, want web1", got.Hosts)
+	}
+}
+
+/*
+ * Return the text of every file in dir
+ */
+func readDir(t *testing.T, dir string) string {
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := ""
+	for _, i := range entries {
+		b, err := ioutil.ReadFile(filepath.Join(dir, i.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		text += string(b)
+	}
+
+	return text
+}
+
+func TestWriteDirDisabledHosts(t *testing.T) {
+
+	dir := t.TempDir()
+
+	cfg := &nrc.NagiosConfig{
+		Hosts: []nrc.Host{
+			{Name: "old1", Alias: "Old", Ipaddress: "10.0.0.1", Disable: "1"},
+			{Name: "web2", Alias: "Web", Ipaddress: "10.0.0.2",
+				Parents: "old1"},
+		},
+		Services: []nrc.Service{
+			{Name: "old1", Command: "check_ping", Svcdesc: "PING"},
+			{Name: "web2", Command: "check_ping", Svcdesc: "PING"},
+		},
+		Hostgroups: []nrc.HostGroup{
+			{Name: "linux", Alias: "Linux", Members: "old1 web2"},
+			{Name: "legacy", Alias: "Legacy", Members: "old1"},
+		},
+		Servicedeps: []nrc.ServiceDependency{
+			{Dephostname: "web2", Depsvcdesc: "PING", Hostname: "old1",
+				Svcdesc: "PING"},
+		},
+		Hostdeps: []nrc.HostDependency{
+			{Dephostname: "web2", Hostname: "old1 web2"},
+		},
+		Hostesc: []nrc.HostEscalation{
+			{Hostname: "old1", Hostgroupname: "linux", Contacts: "ops"},
+			{Hostname: "old1", Contacts: "ops"},
+		},
+		Serviceextinfo: []nrc.ServiceExtInfo{
+			{Hostname: "old1", Svcdesc: "PING", Notes: "old"},
+		},
+		Hostextinfo: []nrc.HostExtInfo{
+			{Hostname: "old1", Notes: "old"},
+		},
+	}
+	if err := cfg.WriteDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if text := readDir(t, dir); strings.Contains(text, "old1") {
+		t.Errorf("disabled host old1 is still referred to:\n%s", text)
+	}
+
+	got, err := nrc.ParseNagiosConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string][2]int{
+		"hosts":          {len(got.Hosts), 1},
+		"services":       {len(got.Services), 1},
+		"hostgroups":     {len(got.Hostgroups), 2},
+		"servicedeps":    {len(got.Servicedeps), 0},
+		"hostdeps":       {len(got.Hostdeps), 1},
+		"hostesc":        {len(got.Hostesc), 1},
+		"serviceextinfo": {len(got.Serviceextinfo), 0},
+		"hostextinfo":    {len(got.Hostextinfo), 0},
+	}
+	for table, n := range counts {
+		if n[0] != n[1] {
+			t.Errorf("read back %d %s, want %d", n[0], table, n[1])
+		}
+	}
+	if len(got.Hosts) == 1 && got.Hosts[0].Parents != "" {
+		t.Errorf("web2 parents %q, want none", got.Hosts[0].Parents)
+	}
+
+	// The config written from is unchanged
+	if len(cfg.Hosts) != 2 || cfg.Hostgroups[0].Members != "old1 web2" {
+		t.Errorf("WriteDir changed its config: %+v", cfg)
+	}
+}
+
+func TestExportNagiosDisabledHostServicesets(t *testing.T) {
+
+	s := nrctest.NewServer()
+	defer s.Close()
+	s.Load("local", "hosts",
+		map[string]string{"name": "old1", "alias": "Old",
+			"ipaddress": "10.0.0.1", "servicesets": "base", "disable": "1"},
+		map[string]string{"name": "web2", "alias": "Web",
+			"ipaddress": "10.0.0.2", "servicesets": "base"})
+	s.Load("local", "servicesets", map[string]string{"name": "base",
+		"command": "check_ping", "svcdesc": "PING"})
+
+	dir := t.TempDir()
+	if err := nrc.ExportNagios(context.Background(), s.NrcClient("local"),
+		dir); err != nil {
+		t.Fatal(err)
+	}
+
+	text := readDir(t, dir)
+	if strings.Contains(text, "old1") {
+		t.Errorf("disabled host old1 is still referred to:\n%s", text)
+	}
+	if !strings.Contains(text, "web2") {
+		t.Errorf("web2 missing from:\n%s", text)
+	}
+}